@@ -22,6 +22,47 @@ import (
 
 const (
 	TunnelFinalizer = "tunnel.cloudflared.cloudflare.com"
+
+	// LoadBalancerPoolAnnotation is the Ingress annotation to route the Ingress hostnames
+	// through the given Cloudflare Load Balancer pool instead of DNS CNAME record.
+	LoadBalancerPoolAnnotation = "cloudflared.cloudflare.com/load-balancer-pool"
+)
+
+// Condition types of Tunnel.
+const (
+	// ReadyCondition is true when every other condition of Tunnel is true.
+	ReadyCondition = "Ready"
+	// CloudTunnelReadyCondition is true when the cloudflare tunnel exists.
+	CloudTunnelReadyCondition = "CloudTunnelReady"
+	// CredentialsReadyCondition is true when the cloudflare credentials and the tunnel token Secret are usable.
+	CredentialsReadyCondition = "CredentialsReady"
+	// RoutesReadyCondition is true when every hostname is routed to the cloudflare tunnel.
+	RoutesReadyCondition = "RoutesReady"
+	// ConfigRenderedCondition is true when the tunnel configuration is rendered into the ConfigMap,
+	// or pushed to cloudflare for remotely managed Tunnel, along with the cloudflared Deployment.
+	ConfigRenderedCondition = "ConfigRendered"
+	// ConnectorsAvailableCondition is true when any cloudflared connector is connected to the cloudflare edge.
+	ConnectorsAvailableCondition = "ConnectorsAvailable"
+)
+
+// Condition reasons of Tunnel.
+const (
+	// ReconciledReason is the reason of a true condition.
+	ReconciledReason = "Reconciled"
+	// ReconcileFailedReason is the reason of a condition whose phase failed, the message holds the error.
+	ReconcileFailedReason = "ReconcileFailed"
+	// ReconcilingReason is the reason of Ready condition while any phase is not observed yet.
+	ReconcilingReason = "Reconciling"
+	// DeletingReason is the reason of Ready condition while Tunnel is being deleted.
+	DeletingReason = "Deleting"
+	// WaitingForConnectorsReason is the reason of ConnectorsAvailableCondition when no connector is connected yet.
+	WaitingForConnectorsReason = "WaitingForConnectors"
+	// TerminatingConnectorsReason is the reason of ConnectorsAvailableCondition while the cloudflared pods
+	// of a Tunnel being deleted terminate.
+	TerminatingConnectorsReason = "TerminatingConnectors"
+	// InvalidConfigReason is the reason of ConfigRenderedCondition when the rendered configuration is rejected
+	// by cloudflared, the message holds the rule error. The previous configuration is kept in place.
+	InvalidConfigReason = "InvalidConfig"
 )
 
 // TunnelIngressRule defines the desired ingress rules of Tunnel
@@ -31,25 +72,118 @@ type TunnelIngressRule struct {
 	Service  string `json:"service"`
 }
 
+// TunnelLoadBalancer defines the Cloudflare Load Balancer routing of Tunnel
+type TunnelLoadBalancer struct {
+	// Pool is the name of Cloudflare Load Balancer pool to which this Tunnel is added as an origin.
+	// Each hostname gets a Load Balancer with this pool, both are created when they do not exist.
+	Pool string `json:"pool"`
+}
+
 // TunnelSpec defines the desired state of Tunnel
 type TunnelSpec struct {
 	TunnelConfigurationSpec `json:",inline,omitempty"`
 	// Ingress Rules configurations for this Tunnel.
 	// +optional
 	IngressRules []TunnelIngressRule `json:"rules,omitempty"`
+	// LoadBalancer routes the hostnames through Cloudflare Load Balancer instead of DNS CNAME record.
+	// This allows the same hostname to be served by Tunnels from several clusters.
+	// +optional
+	LoadBalancer *TunnelLoadBalancer `json:"loadBalancer,omitempty"`
+	// VirtualNetwork is the name of VirtualNetwork used by the TunnelNetworkRoutes of this Tunnel
+	// which do not select any. The default virtual network of the account is used when empty.
+	// +optional
+	VirtualNetwork string `json:"virtualNetwork,omitempty"`
+}
+
+// TunnelLoadBalancerStatus defines the observed Cloudflare Load Balancer pool membership of Tunnel
+type TunnelLoadBalancerStatus struct {
+	// Pool is the name of Cloudflare Load Balancer pool.
+	Pool string `json:"pool"`
+	// PoolID is the ID of Cloudflare Load Balancer pool.
+	// +optional
+	PoolID string `json:"poolID,omitempty"`
+	// Origin is the name of this Tunnel origin in the pool.
+	// +optional
+	Origin string `json:"origin,omitempty"`
+	// Enabled reports whether this Tunnel origin is enabled in the pool.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+}
+
+// TunnelRouteZone defines the Cloudflare zone of a route of Tunnel
+type TunnelRouteZone struct {
+	// Hostname is the routed hostname.
+	Hostname string `json:"hostname"`
+	// Zone is the name of cloudflare zone to which the hostname belongs.
+	Zone string `json:"zone"`
+	// ZoneID is the ID of cloudflare zone to which the hostname belongs.
+	ZoneID string `json:"zoneID"`
+}
+
+// TunnelConnectorStatus defines the observed state of a cloudflared connector of Tunnel
+type TunnelConnectorStatus struct {
+	// ID is the cloudflared connector ID.
+	ID string `json:"id"`
+	// Version is the cloudflared version of the connector.
+	// +optional
+	Version string `json:"version,omitempty"`
+	// Colos is the list of Cloudflare data centers the connector is connected to.
+	// +optional
+	Colos []string `json:"colos,omitempty"`
+	// RunAt is the time the connector started.
+	// +optional
+	RunAt *metav1.Time `json:"runAt,omitempty"`
 }
 
 // TunnelStatus defines the observed state of Tunnel
 type TunnelStatus struct {
+	// ObservedGeneration is the latest generation of Tunnel reconciled by the controller.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions is the observed state of each phase of Tunnel, along with the aggregate Ready condition.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// TunnelID is the ID of cloudflare tunnel.
+	// +optional
+	TunnelID string `json:"tunnelID,omitempty"`
+	// Target is the hostname of cloudflare tunnel, <tunnel-id>.cfargotunnel.com, which DNS records routed to
+	// this Tunnel point to.
+	// +optional
+	Target string `json:"target,omitempty"`
+	// CreatedAt is the creation time of cloudflare tunnel.
+	// +optional
+	CreatedAt *metav1.Time `json:"createdAt,omitempty"`
 	// List of registered route to this Tunnel.
 	Routes []string `json:"routes,omitempty"`
-	// Zone is cloudflare zone
-	Zone string `json:"zone,omitempty"`
+	// Zones is the cloudflare zone of each registered route, matched by the longest hostname suffix.
+	// +optional
+	Zones []TunnelRouteZone `json:"zones,omitempty"`
+	// LoadBalancer is the Cloudflare Load Balancer pool membership of this Tunnel.
+	// +optional
+	LoadBalancer *TunnelLoadBalancerStatus `json:"loadBalancer,omitempty"`
+	// ConnectorCount is the number of cloudflared connectors connected to the Cloudflare edge.
+	// +optional
+	ConnectorCount int32 `json:"connectorCount,omitempty"`
+	// Connectors is the list of cloudflared connectors connected to the Cloudflare edge.
+	// +optional
+	Connectors []TunnelConnectorStatus `json:"connectors,omitempty"`
+	// LastSeen is the latest time a connector of this Tunnel connected to the Cloudflare edge.
+	// It is kept when no connector is connected anymore.
+	// +optional
+	LastSeen *metav1.Time `json:"lastSeen,omitempty"`
+
+	// NetworkRoutes is the list of private networks routed to this Tunnel by TunnelNetworkRoutes.
+	// +optional
+	NetworkRoutes []string `json:"networkRoutes,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
-// +kubebuilder:printcolumn:name="ZONE",type="string",JSONPath=".status.zone",description="Zone to which this Tunnel belongs"
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status",description="Whether this Tunnel is ready"
+// +kubebuilder:printcolumn:name="TUNNEL ID",type="string",JSONPath=".status.tunnelID",description="ID of cloudflare tunnel"
+// +kubebuilder:printcolumn:name="CONNECTORS",type="integer",JSONPath=".status.connectorCount",description="Number of connected cloudflared connectors"
 // +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
 
 // Tunnel is the Schema for the tunnels API
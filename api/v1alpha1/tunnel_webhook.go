@@ -0,0 +1,77 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+func (r *Tunnel) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-cloudflared-cloudflare-com-v1alpha1-tunnel,mutating=true,failurePolicy=fail,sideEffects=None,groups=cloudflared.cloudflare.com,resources=tunnels,verbs=create;update,versions=v1alpha1,name=mtunnel.kb.io,admissionReviewVersions={v1,v1beta1}
+
+var _ webhook.Defaulter = &Tunnel{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+func (r *Tunnel) Default() {
+	r.Spec.TunnelConfigurationSpec.Default()
+}
+
+//+kubebuilder:webhook:path=/validate-cloudflared-cloudflare-com-v1alpha1-tunnel,mutating=false,failurePolicy=fail,sideEffects=None,groups=cloudflared.cloudflare.com,resources=tunnels,verbs=create;update,versions=v1alpha1,name=vtunnel.kb.io,admissionReviewVersions={v1,v1beta1}
+
+var _ webhook.Validator = &Tunnel{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *Tunnel) ValidateCreate() error {
+	return r.validate()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *Tunnel) ValidateUpdate(old runtime.Object) error {
+	// A Tunnel being deleted or keeping its spec, e.g. on finalizer removal, must not be
+	// blocked by a spec that got admitted before validation was introduced.
+	if oldTunnel, ok := old.(*Tunnel); ok && (r.DeletionTimestamp != nil || equality.Semantic.DeepEqual(r.Spec, oldTunnel.Spec)) {
+		return nil
+	}
+
+	return r.validate()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *Tunnel) ValidateDelete() error {
+	return nil
+}
+
+func (r *Tunnel) validate() error {
+	specPath := field.NewPath("spec")
+	allErrs := r.Spec.TunnelConfigurationSpec.validate(specPath)
+	allErrs = append(allErrs, validateIngressRules(r.Spec.IngressRules, specPath.Child("rules"))...)
+	if len(allErrs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(GroupVersion.WithKind("Tunnel").GroupKind(), r.Name, allErrs)
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestTunnel_ValidateCreate(t *testing.T) {
+	catchAll := TunnelIngressRule{Service: "http_status:404"}
+	tests := []struct {
+		name    string
+		spec    TunnelSpec
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			spec: TunnelSpec{
+				TunnelConfigurationSpec: TunnelConfigurationSpec{
+					OriginCert:    &corev1.TypedLocalObjectReference{Kind: "Secret", Name: "origincert"},
+					OriginRequest: &TunnelOriginRequest{ConnectTimeout: "10s", KeepAliveTimeout: "1m30s"},
+				},
+				IngressRules: []TunnelIngressRule{
+					{Hostname: "app.example.com", Path: "^/api/.*", Service: "http://app.default:80"},
+					{Hostname: "*.example.com", Service: "https://web.default"},
+					{Hostname: "ssh.example.com", Service: "ssh://bastion.default:22"},
+					{Hostname: "socket.example.com", Service: "unix:/var/run/app.sock"},
+					catchAll,
+				},
+			},
+		},
+		{
+			name: "no rules",
+		},
+		{
+			name: "origincert not a secret",
+			spec: TunnelSpec{
+				TunnelConfigurationSpec: TunnelConfigurationSpec{
+					OriginCert: &corev1.TypedLocalObjectReference{Kind: "ConfigMap", Name: "origincert"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "malformed duration",
+			spec: TunnelSpec{
+				TunnelConfigurationSpec: TunnelConfigurationSpec{
+					OriginRequest: &TunnelOriginRequest{ConnectTimeout: "30"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing catch-all rule",
+			spec: TunnelSpec{
+				IngressRules: []TunnelIngressRule{
+					{Hostname: "app.example.com", Service: "http://app.default:80"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid hostname",
+			spec: TunnelSpec{
+				IngressRules: []TunnelIngressRule{
+					{Hostname: "app_example.com", Service: "http://app.default:80"},
+					catchAll,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid path regex",
+			spec: TunnelSpec{
+				IngressRules: []TunnelIngressRule{
+					{Hostname: "app.example.com", Path: "/api/(", Service: "http://app.default:80"},
+					catchAll,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unsupported service scheme",
+			spec: TunnelSpec{
+				IngressRules: []TunnelIngressRule{
+					{Hostname: "app.example.com", Service: "ftp://app.default:21"},
+					catchAll,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "service with path",
+			spec: TunnelSpec{
+				IngressRules: []TunnelIngressRule{
+					{Hostname: "app.example.com", Service: "http://app.default:80/api"},
+					catchAll,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid http status",
+			spec: TunnelSpec{
+				IngressRules: []TunnelIngressRule{
+					{Service: "http_status:not-found"},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tunnel := &Tunnel{Spec: tt.spec}
+			if err := tunnel.ValidateCreate(); (err != nil) != tt.wantErr {
+				t.Errorf("Tunnel.ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTunnel_ValidateUpdate(t *testing.T) {
+	invalid := TunnelSpec{
+		IngressRules: []TunnelIngressRule{
+			{Hostname: "app.example.com", Service: "http://app.default:80"},
+		},
+	}
+	tests := []struct {
+		name    string
+		old     TunnelSpec
+		new     *Tunnel
+		wantErr bool
+	}{
+		{
+			name: "invalid spec update",
+			new: &Tunnel{
+				Spec: invalid,
+			},
+			wantErr: true,
+		},
+		{
+			name: "finalizer removal of invalid spec",
+			old:  invalid,
+			new: &Tunnel{
+				Spec: invalid,
+			},
+		},
+		{
+			name: "update while deleting",
+			new: &Tunnel{
+				ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &metav1.Time{}},
+				Spec:       invalid,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := &Tunnel{Spec: tt.old}
+			if err := tt.new.ValidateUpdate(old); (err != nil) != tt.wantErr {
+				t.Errorf("Tunnel.ValidateUpdate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTunnelConfigurationSpec_Default(t *testing.T) {
+	tests := []struct {
+		name string
+		spec TunnelConfigurationSpec
+		want TunnelConfigurationSpec
+	}{
+		{
+			name: "no origin request",
+		},
+		{
+			name: "unset origin request",
+			spec: TunnelConfigurationSpec{
+				OriginRequest: &TunnelOriginRequest{ConnectTimeout: "10s", NoTLSVerify: true},
+			},
+			want: TunnelConfigurationSpec{
+				OriginRequest: &TunnelOriginRequest{
+					ConnectTimeout:       "10s",
+					TLSTimeout:           DefaultTLSTimeout,
+					TCPKeepAlive:         DefaultTCPKeepAlive,
+					KeepAliveConnections: DefaultKeepAliveConnections,
+					KeepAliveTimeout:     DefaultKeepAliveTimeout,
+					NoTLSVerify:          true,
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.spec.Default()
+			if !reflect.DeepEqual(tt.spec, tt.want) {
+				t.Errorf("TunnelConfigurationSpec.Default() = %+v, want %+v", tt.spec, tt.want)
+			}
+		})
+	}
+}
@@ -26,55 +26,104 @@ type TunnelOriginRequest struct {
 	// Timeout for establishing a new TCP connection to your origin server.
 	// This excludes the time taken to establish TLS. (Default: 30s)
 	// +optional
-	ConnectTimeout string `json:"connectTimeout"`
+	ConnectTimeout string `json:"connectTimeout,omitempty"`
 
 	// Timeout for completing a TLS handshake to your origin server,
 	// if you have chosen to connect Tunnel to an HTTPS server. (Default: 10s)
 	// +optional
-	TLSTimeout string `json:"tlsTimeout"`
+	TLSTimeout string `json:"tlsTimeout,omitempty"`
 
 	// Disables chunked transfer encoding. Useful if you are running
 	// a WSGI server. (Default: false)
 	// +optional
-	DisableChunkedEncoding bool `json:"disableChunkedEncoding"`
+	DisableChunkedEncoding bool `json:"disableChunkedEncoding,omitempty"`
 
 	// Sets the HTTP Host header on requests sent to the local service.
 	// +optional
-	HTTPHostHeader string `json:"httpHostHeader"`
+	HTTPHostHeader string `json:"httpHostHeader,omitempty"`
 
 	// The timeout after which a TCP keepalive packet is sent on a connection
 	// between Tunnel and the origin server. (Default: 30s)
 	// +optional
-	TCPKeepAlive string `json:"tcpKeepAlive"`
+	TCPKeepAlive string `json:"tcpKeepAlive,omitempty"`
 
 	// Maximum number of idle keepalive connections between Tunnel and your origin.
 	// This does not restrict the total number of concurrent connections. (Default: 100)
 	// +optional
-	KeepAliveConnections int32 `json:"keepAliveConnections"`
+	KeepAliveConnections int32 `json:"keepAliveConnections,omitempty"`
 
 	// Timeout after which an idle keepalive connection can be discarded. (Default: 1m30s)
 	// +optional
-	KeepAliveTimeout string `json:"keepAliveTimeout"`
+	KeepAliveTimeout string `json:"keepAliveTimeout,omitempty"`
 
 	// Disables TLS verification of the certificate presented by your origin.
 	// Will allow any certificate from the origin to be accepted. (Default: false)
 	// +optional
-	NoTLSVerify bool `json:"noTLSVerify"`
+	NoTLSVerify bool `json:"noTLSVerify,omitempty"`
 
 	// Hostname that cloudflared should expect from your origin server certificate.
 	// +optional
-	OriginServerName string `json:"originServerName"`
+	OriginServerName string `json:"originServerName,omitempty"`
 }
 
+// TunnelConfigSource is where cloudflared gets the Tunnel configuration from.
+// +kubebuilder:validation:Enum=local;cloudflare
+type TunnelConfigSource string
+
+const (
+	// TunnelConfigSourceLocal renders the configuration into a ConfigMap mounted into cloudflared.
+	// cloudflared is restarted to pick up every configuration change.
+	TunnelConfigSourceLocal TunnelConfigSource = "local"
+	// TunnelConfigSourceCloudflare pushes the configuration to Cloudflare which serves it to
+	// cloudflared running with the tunnel token. Configuration changes take effect without restart.
+	TunnelConfigSourceCloudflare TunnelConfigSource = "cloudflare"
+)
+
+// TunnelCredentialsSource is what cloudflared authenticates as the Tunnel with.
+// +kubebuilder:validation:Enum=token;credentialsFile
+type TunnelCredentialsSource string
+
+const (
+	// TunnelCredentialsSourceToken runs cloudflared with the tunnel token from an env var.
+	TunnelCredentialsSourceToken TunnelCredentialsSource = "token"
+	// TunnelCredentialsSourceFile mounts the tunnel credentials file into cloudflared, e.g. for
+	// cloudflared versions not supporting the tunnel token.
+	TunnelCredentialsSourceFile TunnelCredentialsSource = "credentialsFile"
+)
+
 // TunnelConfigurationSpec defines the desired state of TunnelConfiguration
 type TunnelConfigurationSpec struct {
 	// OriginCert is a reference to a object that contains cloudflare tunnel origincert.
+	// The account and zone are taken from the origincert unless AccountID and Zone or ZoneID are set.
+	// +optional
 	OriginCert *corev1.TypedLocalObjectReference `json:"originCert,omitempty"`
+	// APITokenSecretRef is a reference to the key of a Secret, in the same namespace, holding the
+	// Cloudflare API token. The controller-wide CF_API_TOKEN is used when it is not set.
+	// +optional
+	APITokenSecretRef *corev1.SecretKeySelector `json:"apiTokenSecretRef,omitempty"`
+	// AccountID is the Cloudflare account ID. Required when OriginCert is not set.
+	// +optional
+	AccountID string `json:"accountID,omitempty"`
+	// ZoneID is the Cloudflare zone ID. Takes precedence over Zone.
+	// +optional
+	ZoneID string `json:"zoneID,omitempty"`
+	// Zone is the Cloudflare zone name, resolved into the zone ID when ZoneID is not set.
+	// Either Zone or ZoneID is required when OriginCert is not set.
+	// +optional
+	Zone string `json:"zone,omitempty"`
 
 	// OriginRequest is optional origin configurations. See
 	// https://developers.cloudflare.com/cloudflare-one/connections/connect-apps/configuration/ingress#origin-configurations
 	// +optional
 	OriginRequest *TunnelOriginRequest `json:"originRequest,omitempty"`
+	// ConfigSource is where cloudflared gets the Tunnel configuration from,
+	// either "local" (default) or "cloudflare".
+	// +optional
+	ConfigSource TunnelConfigSource `json:"configSource,omitempty"`
+	// CredentialsSource is what cloudflared authenticates as the Tunnel with,
+	// either "token" (default) or "credentialsFile".
+	// +optional
+	CredentialsSource TunnelCredentialsSource `json:"credentialsSource,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -0,0 +1,255 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// Default originRequest configurations, the same as cloudflared defaults.
+const (
+	DefaultConnectTimeout       = "30s"
+	DefaultTLSTimeout           = "10s"
+	DefaultTCPKeepAlive         = "30s"
+	DefaultKeepAliveConnections = 100
+	DefaultKeepAliveTimeout     = "1m30s"
+)
+
+// serviceSchemes is the set of origin service URL schemes understood by cloudflared.
+var serviceSchemes = map[string]bool{
+	"http":     true,
+	"https":    true,
+	"unix":     true,
+	"unix+tls": true,
+	"tcp":      true,
+	"ssh":      true,
+	"rdp":      true,
+	"smb":      true,
+	"ws":       true,
+	"wss":      true,
+}
+
+func (r *TunnelConfiguration) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-cloudflared-cloudflare-com-v1alpha1-tunnelconfiguration,mutating=true,failurePolicy=fail,sideEffects=None,groups=cloudflared.cloudflare.com,resources=tunnelconfigurations,verbs=create;update,versions=v1alpha1,name=mtunnelconfiguration.kb.io,admissionReviewVersions={v1,v1beta1}
+
+var _ webhook.Defaulter = &TunnelConfiguration{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+func (r *TunnelConfiguration) Default() {
+	r.Spec.Default()
+}
+
+//+kubebuilder:webhook:path=/validate-cloudflared-cloudflare-com-v1alpha1-tunnelconfiguration,mutating=false,failurePolicy=fail,sideEffects=None,groups=cloudflared.cloudflare.com,resources=tunnelconfigurations,verbs=create;update,versions=v1alpha1,name=vtunnelconfiguration.kb.io,admissionReviewVersions={v1,v1beta1}
+
+var _ webhook.Validator = &TunnelConfiguration{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *TunnelConfiguration) ValidateCreate() error {
+	return r.validate()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *TunnelConfiguration) ValidateUpdate(old runtime.Object) error {
+	// Metadata only updates, e.g. finalizer changes, are admitted as is.
+	if oldConfig, ok := old.(*TunnelConfiguration); ok && (r.DeletionTimestamp != nil || equality.Semantic.DeepEqual(r.Spec, oldConfig.Spec)) {
+		return nil
+	}
+
+	return r.validate()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *TunnelConfiguration) ValidateDelete() error {
+	return nil
+}
+
+func (r *TunnelConfiguration) validate() error {
+	allErrs := r.Spec.validate(field.NewPath("spec"))
+	if len(allErrs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(GroupVersion.WithKind("TunnelConfiguration").GroupKind(), r.Name, allErrs)
+}
+
+// Default sets the unset originRequest configurations to the cloudflared defaults.
+func (s *TunnelConfigurationSpec) Default() {
+	or := s.OriginRequest
+	if or == nil {
+		return
+	}
+
+	if or.ConnectTimeout == "" {
+		or.ConnectTimeout = DefaultConnectTimeout
+	}
+
+	if or.TLSTimeout == "" {
+		or.TLSTimeout = DefaultTLSTimeout
+	}
+
+	if or.TCPKeepAlive == "" {
+		or.TCPKeepAlive = DefaultTCPKeepAlive
+	}
+
+	if or.KeepAliveConnections == 0 {
+		or.KeepAliveConnections = DefaultKeepAliveConnections
+	}
+
+	if or.KeepAliveTimeout == "" {
+		or.KeepAliveTimeout = DefaultKeepAliveTimeout
+	}
+}
+
+func (s *TunnelConfigurationSpec) validate(fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if ref := s.OriginCert; ref != nil {
+		if ref.APIGroup != nil && *ref.APIGroup != "" {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("originCert", "apiGroup"), *ref.APIGroup, "must be empty"))
+		}
+
+		if ref.Kind != "Secret" {
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child("originCert", "kind"), ref.Kind, []string{"Secret"}))
+		}
+	}
+
+	if or := s.OriginRequest; or != nil {
+		orPath := fldPath.Child("originRequest")
+		durations := []struct {
+			name  string
+			value string
+		}{
+			{"connectTimeout", or.ConnectTimeout},
+			{"tlsTimeout", or.TLSTimeout},
+			{"tcpKeepAlive", or.TCPKeepAlive},
+			{"keepAliveTimeout", or.KeepAliveTimeout},
+		}
+		for _, d := range durations {
+			if d.value == "" {
+				continue
+			}
+
+			if duration, err := time.ParseDuration(d.value); err != nil {
+				allErrs = append(allErrs, field.Invalid(orPath.Child(d.name), d.value, err.Error()))
+			} else if duration < 0 {
+				allErrs = append(allErrs, field.Invalid(orPath.Child(d.name), d.value, "must not be negative"))
+			}
+		}
+
+		if or.KeepAliveConnections < 0 {
+			allErrs = append(allErrs, field.Invalid(orPath.Child("keepAliveConnections"), or.KeepAliveConnections, "must not be negative"))
+		}
+	}
+
+	return allErrs
+}
+
+// validateIngressRules validates the rules the way cloudflared does when loading its configuration.
+// The last rule must be a catch-all rule, matching any hostname and path.
+func validateIngressRules(rules []TunnelIngressRule, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, rule := range rules {
+		rulePath := fldPath.Index(i)
+		if rule.Hostname != "" {
+			hostname := strings.TrimPrefix(rule.Hostname, "*.")
+			for _, msg := range validation.IsDNS1123Subdomain(hostname) {
+				allErrs = append(allErrs, field.Invalid(rulePath.Child("hostname"), rule.Hostname, msg))
+			}
+		}
+
+		if rule.Path != "" {
+			if _, err := regexp.Compile(rule.Path); err != nil {
+				allErrs = append(allErrs, field.Invalid(rulePath.Child("path"), rule.Path, err.Error()))
+			}
+		}
+
+		if err := validateService(rule.Service); err != nil {
+			allErrs = append(allErrs, field.Invalid(rulePath.Child("service"), rule.Service, err.Error()))
+		}
+	}
+
+	if len(rules) != 0 {
+		last := rules[len(rules)-1]
+		if last.Hostname != "" || last.Path != "" {
+			allErrs = append(allErrs, field.Invalid(fldPath.Index(len(rules)-1), last.Hostname+last.Path,
+				"the last rule must be a catch-all rule without hostname and path"))
+		}
+	}
+
+	return allErrs
+}
+
+// validateService validates the origin service of a rule.
+func validateService(service string) error {
+	switch {
+	case service == "":
+		return fmt.Errorf("must not be empty")
+	case service == "hello_world", service == "bastion":
+		return nil
+	case strings.HasPrefix(service, "http_status:"):
+		code, err := strconv.Atoi(strings.TrimPrefix(service, "http_status:"))
+		if err != nil || code < 100 || code > 599 {
+			return fmt.Errorf("must be a valid http status code")
+		}
+
+		return nil
+	}
+
+	u, err := url.Parse(service)
+	if err != nil {
+		return err
+	}
+
+	if !serviceSchemes[u.Scheme] {
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+
+	if u.Scheme == "unix" || u.Scheme == "unix+tls" {
+		if u.Path == "" {
+			return fmt.Errorf("must have a socket path")
+		}
+
+		return nil
+	}
+
+	if u.Hostname() == "" {
+		return fmt.Errorf("must have a host")
+	}
+
+	if u.Path != "" && u.Path != "/" {
+		return fmt.Errorf("must not have a path, use the path of the rule instead")
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestTunnelConfiguration_ValidateCreate(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    TunnelConfigurationSpec
+		wantErr bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "valid",
+			spec: TunnelConfigurationSpec{
+				OriginCert:    &corev1.TypedLocalObjectReference{Kind: "Secret", Name: "origincert"},
+				OriginRequest: &TunnelOriginRequest{ConnectTimeout: "10s", TLSTimeout: "5s", KeepAliveConnections: 10},
+			},
+		},
+		{
+			name: "origincert with api group",
+			spec: TunnelConfigurationSpec{
+				OriginCert: &corev1.TypedLocalObjectReference{APIGroup: stringPtr("v1"), Kind: "Secret", Name: "origincert"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "origincert not a secret",
+			spec: TunnelConfigurationSpec{
+				OriginCert: &corev1.TypedLocalObjectReference{Kind: "ConfigMap", Name: "origincert"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "malformed duration",
+			spec: TunnelConfigurationSpec{
+				OriginRequest: &TunnelOriginRequest{TCPKeepAlive: "thirty seconds"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &TunnelConfiguration{Spec: tt.spec}
+			if err := config.ValidateCreate(); (err != nil) != tt.wantErr {
+				t.Errorf("TunnelConfiguration.ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTunnelConfiguration_ValidateUpdate(t *testing.T) {
+	invalid := TunnelConfigurationSpec{
+		OriginRequest: &TunnelOriginRequest{ConnectTimeout: "30"},
+	}
+	tests := []struct {
+		name    string
+		old     TunnelConfigurationSpec
+		new     *TunnelConfiguration
+		wantErr bool
+	}{
+		{
+			name: "invalid spec update",
+			new: &TunnelConfiguration{
+				Spec: invalid,
+			},
+			wantErr: true,
+		},
+		{
+			name: "metadata update of invalid spec",
+			old:  invalid,
+			new: &TunnelConfiguration{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"foo": "bar"}},
+				Spec:       invalid,
+			},
+		},
+		{
+			name: "finalizer removal while deleting",
+			new: &TunnelConfiguration{
+				ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &metav1.Time{}},
+				Spec:       invalid,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := &TunnelConfiguration{Spec: tt.old}
+			if err := tt.new.ValidateUpdate(old); (err != nil) != tt.wantErr {
+				t.Errorf("TunnelConfiguration.ValidateUpdate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTunnelConfiguration_Default(t *testing.T) {
+	config := &TunnelConfiguration{
+		Spec: TunnelConfigurationSpec{
+			OriginRequest: &TunnelOriginRequest{ConnectTimeout: "10s"},
+		},
+	}
+
+	config.Default()
+	if got := config.Spec.OriginRequest; got.ConnectTimeout != "10s" || got.TLSTimeout != DefaultTLSTimeout || got.KeepAliveConnections != DefaultKeepAliveConnections {
+		t.Errorf("TunnelConfiguration.Default() = %+v", got)
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	TunnelNetworkRouteFinalizer = "tunnelnetworkroute.cloudflared.cloudflare.com"
+)
+
+// TunnelNetworkRouteSpec defines the desired state of TunnelNetworkRoute
+type TunnelNetworkRouteSpec struct {
+	// TunnelRef is a reference to the Tunnel in the same namespace through which the CIDRs are routed.
+	TunnelRef corev1.LocalObjectReference `json:"tunnelRef"`
+	// CIDRs is the list of private networks, in CIDR notation, routed through the Tunnel.
+	// +kubebuilder:validation:MinItems=1
+	CIDRs []string `json:"cidrs"`
+	// Comment is an optional description of the routes.
+	// +optional
+	Comment string `json:"comment,omitempty"`
+	// VirtualNetwork is the name of VirtualNetwork the CIDRs are routed in. Defaults to
+	// the virtual network of the Tunnel, or the default virtual network of the account.
+	// +optional
+	VirtualNetwork string `json:"virtualNetwork,omitempty"`
+}
+
+// TunnelNetworkRouteEntry defines an observed private network route of Cloudflare
+type TunnelNetworkRouteEntry struct {
+	// ID is the Cloudflare route ID.
+	ID string `json:"id"`
+	// Network is the private network in CIDR notation.
+	Network string `json:"network"`
+}
+
+// TunnelNetworkRouteStatus defines the observed state of TunnelNetworkRoute
+type TunnelNetworkRouteStatus struct {
+	// TunnelID is the ID of Cloudflare tunnel through which the CIDRs are routed.
+	// +optional
+	TunnelID string `json:"tunnelID,omitempty"`
+	// VirtualNetworkID is the ID of Cloudflare virtual network the CIDRs are routed in.
+	// +optional
+	VirtualNetworkID string `json:"virtualNetworkID,omitempty"`
+	// Routes is the list of registered private network routes.
+	// +optional
+	Routes []TunnelNetworkRouteEntry `json:"routes,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="TUNNEL",type="string",JSONPath=".spec.tunnelRef.name",description="Tunnel through which the CIDRs are routed"
+// +kubebuilder:printcolumn:name="CIDRS",type="string",JSONPath=".spec.cidrs",description="Routed private networks"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+
+// TunnelNetworkRoute is the Schema for the tunnelnetworkroutes API
+type TunnelNetworkRoute struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   TunnelNetworkRouteSpec   `json:"spec,omitempty"`
+	Status TunnelNetworkRouteStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// TunnelNetworkRouteList contains a list of TunnelNetworkRoute
+type TunnelNetworkRouteList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []TunnelNetworkRoute `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&TunnelNetworkRoute{}, &TunnelNetworkRouteList{})
+}
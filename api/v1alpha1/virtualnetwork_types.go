@@ -0,0 +1,114 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	VirtualNetworkFinalizer = "virtualnetwork.cloudflared.cloudflare.com"
+)
+
+// Condition types of VirtualNetwork.
+const (
+	// DefaultNetworkCondition is true when the VirtualNetwork desiring to be the default virtual network
+	// of the account is made the default.
+	DefaultNetworkCondition = "DefaultNetwork"
+)
+
+// Condition reasons of VirtualNetwork.
+const (
+	// DefaultConflictReason is the reason of DefaultNetworkCondition when an older VirtualNetwork of the
+	// same account desires to be the default too, the message holds its name. The older one is kept the default.
+	DefaultConflictReason = "DefaultConflict"
+)
+
+// TunnelConfigurationReference is a reference to a TunnelConfiguration in another namespace
+type TunnelConfigurationReference struct {
+	// Namespace of the TunnelConfiguration. Only the cluster resource namespace of the controller
+	// is accepted, so the credentials of the other namespaces can not be used by cluster scoped resources.
+	Namespace string `json:"namespace"`
+	// Name of the TunnelConfiguration.
+	Name string `json:"name"`
+}
+
+// VirtualNetworkSpec defines the desired state of VirtualNetwork
+type VirtualNetworkSpec struct {
+	// ConfigurationRef is a reference to the TunnelConfiguration whose credentials are used
+	// to manage the Cloudflare virtual network. It must be in the cluster resource namespace
+	// of the controller, cloudflared-system by default.
+	ConfigurationRef TunnelConfigurationReference `json:"configurationRef"`
+	// Comment is an optional description of the virtual network.
+	// +optional
+	Comment string `json:"comment,omitempty"`
+	// Default makes this the default virtual network of the account, which is used by
+	// the routes and WARP clients not selecting any virtual network. Cloudflare always
+	// has one default virtual network, so unsetting it takes effect only once another
+	// virtual network becomes the default. When several VirtualNetworks of the account
+	// set it, only the oldest one is made the default.
+	// +optional
+	Default bool `json:"default,omitempty"`
+}
+
+// VirtualNetworkStatus defines the observed state of VirtualNetwork
+type VirtualNetworkStatus struct {
+	// ID is the Cloudflare virtual network ID.
+	// +optional
+	ID string `json:"id,omitempty"`
+	// Default reports whether this is the default virtual network of the account.
+	// +optional
+	Default bool `json:"default,omitempty"`
+	// Created reports whether the Cloudflare virtual network was created for this VirtualNetwork.
+	// Only a created virtual network is deleted along with the VirtualNetwork, an adopted one is left as is.
+	// +optional
+	Created bool `json:"created,omitempty"`
+	// Conditions is the observed state of VirtualNetwork.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="ID",type="string",JSONPath=".status.id",description="Cloudflare virtual network ID"
+// +kubebuilder:printcolumn:name="DEFAULT",type="boolean",JSONPath=".status.default",description="Whether this is the default virtual network"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+
+// VirtualNetwork is the Schema for the virtualnetworks API
+type VirtualNetwork struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VirtualNetworkSpec   `json:"spec,omitempty"`
+	Status VirtualNetworkStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// VirtualNetworkList contains a list of VirtualNetwork
+type VirtualNetworkList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []VirtualNetwork `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&VirtualNetwork{}, &VirtualNetworkList{})
+}
@@ -21,8 +21,9 @@ limitations under the License.
 package v1alpha1
 
 import (
-	"k8s.io/api/core/v1"
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -110,12 +111,32 @@ func (in *TunnelConfigurationList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TunnelConfigurationReference) DeepCopyInto(out *TunnelConfigurationReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TunnelConfigurationReference.
+func (in *TunnelConfigurationReference) DeepCopy() *TunnelConfigurationReference {
+	if in == nil {
+		return nil
+	}
+	out := new(TunnelConfigurationReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TunnelConfigurationSpec) DeepCopyInto(out *TunnelConfigurationSpec) {
 	*out = *in
 	if in.OriginCert != nil {
 		in, out := &in.OriginCert, &out.OriginCert
-		*out = new(v1.TypedLocalObjectReference)
+		*out = new(corev1.TypedLocalObjectReference)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.APITokenSecretRef != nil {
+		in, out := &in.APITokenSecretRef, &out.APITokenSecretRef
+		*out = new(corev1.SecretKeySelector)
 		(*in).DeepCopyInto(*out)
 	}
 	if in.OriginRequest != nil {
@@ -135,6 +156,30 @@ func (in *TunnelConfigurationSpec) DeepCopy() *TunnelConfigurationSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TunnelConnectorStatus) DeepCopyInto(out *TunnelConnectorStatus) {
+	*out = *in
+	if in.Colos != nil {
+		in, out := &in.Colos, &out.Colos
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RunAt != nil {
+		in, out := &in.RunAt, &out.RunAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TunnelConnectorStatus.
+func (in *TunnelConnectorStatus) DeepCopy() *TunnelConnectorStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TunnelConnectorStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TunnelIngressRule) DeepCopyInto(out *TunnelIngressRule) {
 	*out = *in
@@ -182,6 +227,151 @@ func (in *TunnelList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TunnelLoadBalancer) DeepCopyInto(out *TunnelLoadBalancer) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TunnelLoadBalancer.
+func (in *TunnelLoadBalancer) DeepCopy() *TunnelLoadBalancer {
+	if in == nil {
+		return nil
+	}
+	out := new(TunnelLoadBalancer)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TunnelLoadBalancerStatus) DeepCopyInto(out *TunnelLoadBalancerStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TunnelLoadBalancerStatus.
+func (in *TunnelLoadBalancerStatus) DeepCopy() *TunnelLoadBalancerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TunnelLoadBalancerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TunnelNetworkRoute) DeepCopyInto(out *TunnelNetworkRoute) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TunnelNetworkRoute.
+func (in *TunnelNetworkRoute) DeepCopy() *TunnelNetworkRoute {
+	if in == nil {
+		return nil
+	}
+	out := new(TunnelNetworkRoute)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TunnelNetworkRoute) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TunnelNetworkRouteEntry) DeepCopyInto(out *TunnelNetworkRouteEntry) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TunnelNetworkRouteEntry.
+func (in *TunnelNetworkRouteEntry) DeepCopy() *TunnelNetworkRouteEntry {
+	if in == nil {
+		return nil
+	}
+	out := new(TunnelNetworkRouteEntry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TunnelNetworkRouteList) DeepCopyInto(out *TunnelNetworkRouteList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]TunnelNetworkRoute, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TunnelNetworkRouteList.
+func (in *TunnelNetworkRouteList) DeepCopy() *TunnelNetworkRouteList {
+	if in == nil {
+		return nil
+	}
+	out := new(TunnelNetworkRouteList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TunnelNetworkRouteList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TunnelNetworkRouteSpec) DeepCopyInto(out *TunnelNetworkRouteSpec) {
+	*out = *in
+	out.TunnelRef = in.TunnelRef
+	if in.CIDRs != nil {
+		in, out := &in.CIDRs, &out.CIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TunnelNetworkRouteSpec.
+func (in *TunnelNetworkRouteSpec) DeepCopy() *TunnelNetworkRouteSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TunnelNetworkRouteSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TunnelNetworkRouteStatus) DeepCopyInto(out *TunnelNetworkRouteStatus) {
+	*out = *in
+	if in.Routes != nil {
+		in, out := &in.Routes, &out.Routes
+		*out = make([]TunnelNetworkRouteEntry, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TunnelNetworkRouteStatus.
+func (in *TunnelNetworkRouteStatus) DeepCopy() *TunnelNetworkRouteStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TunnelNetworkRouteStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TunnelOriginRequest) DeepCopyInto(out *TunnelOriginRequest) {
 	*out = *in
@@ -197,6 +387,21 @@ func (in *TunnelOriginRequest) DeepCopy() *TunnelOriginRequest {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TunnelRouteZone) DeepCopyInto(out *TunnelRouteZone) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TunnelRouteZone.
+func (in *TunnelRouteZone) DeepCopy() *TunnelRouteZone {
+	if in == nil {
+		return nil
+	}
+	out := new(TunnelRouteZone)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TunnelSpec) DeepCopyInto(out *TunnelSpec) {
 	*out = *in
@@ -206,6 +411,11 @@ func (in *TunnelSpec) DeepCopyInto(out *TunnelSpec) {
 		*out = make([]TunnelIngressRule, len(*in))
 		copy(*out, *in)
 	}
+	if in.LoadBalancer != nil {
+		in, out := &in.LoadBalancer, &out.LoadBalancer
+		*out = new(TunnelLoadBalancer)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TunnelSpec.
@@ -221,11 +431,48 @@ func (in *TunnelSpec) DeepCopy() *TunnelSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TunnelStatus) DeepCopyInto(out *TunnelStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CreatedAt != nil {
+		in, out := &in.CreatedAt, &out.CreatedAt
+		*out = (*in).DeepCopy()
+	}
 	if in.Routes != nil {
 		in, out := &in.Routes, &out.Routes
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Zones != nil {
+		in, out := &in.Zones, &out.Zones
+		*out = make([]TunnelRouteZone, len(*in))
+		copy(*out, *in)
+	}
+	if in.LoadBalancer != nil {
+		in, out := &in.LoadBalancer, &out.LoadBalancer
+		*out = new(TunnelLoadBalancerStatus)
+		**out = **in
+	}
+	if in.Connectors != nil {
+		in, out := &in.Connectors, &out.Connectors
+		*out = make([]TunnelConnectorStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastSeen != nil {
+		in, out := &in.LastSeen, &out.LastSeen
+		*out = (*in).DeepCopy()
+	}
+	if in.NetworkRoutes != nil {
+		in, out := &in.NetworkRoutes, &out.NetworkRoutes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TunnelStatus.
@@ -237,3 +484,100 @@ func (in *TunnelStatus) DeepCopy() *TunnelStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualNetwork) DeepCopyInto(out *VirtualNetwork) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualNetwork.
+func (in *VirtualNetwork) DeepCopy() *VirtualNetwork {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualNetwork)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VirtualNetwork) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualNetworkList) DeepCopyInto(out *VirtualNetworkList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]VirtualNetwork, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualNetworkList.
+func (in *VirtualNetworkList) DeepCopy() *VirtualNetworkList {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualNetworkList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VirtualNetworkList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualNetworkSpec) DeepCopyInto(out *VirtualNetworkSpec) {
+	*out = *in
+	out.ConfigurationRef = in.ConfigurationRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualNetworkSpec.
+func (in *VirtualNetworkSpec) DeepCopy() *VirtualNetworkSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualNetworkSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualNetworkStatus) DeepCopyInto(out *VirtualNetworkStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualNetworkStatus.
+func (in *VirtualNetworkStatus) DeepCopy() *VirtualNetworkStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualNetworkStatus)
+	in.DeepCopyInto(out)
+	return out
+}
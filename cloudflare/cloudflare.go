@@ -17,10 +17,10 @@ limitations under the License.
 package cloudflare
 
 import (
-	"context"
 	"errors"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/cloudflare/cloudflared/certutil"
 	"github.com/go-logr/logr"
@@ -37,6 +37,7 @@ const (
 var (
 	ErrNotFound                 = errors.New("not found")
 	ErrUnexpectedMultipleTunnel = errors.New("unexpected have multiple tunnel with same name")
+	ErrStopPaging               = errors.New("stop paging")
 )
 
 type Client interface {
@@ -45,12 +46,27 @@ type Client interface {
 
 	Tunnels() TunnelClient
 	Zones() ZoneClient
+	DNSRecords() DNSRecordClient
+	LoadBalancers() LoadBalancerClient
+	NetworkRoutes() NetworkRouteClient
+	VirtualNetworks() VirtualNetworkClient
 }
 
 type client struct {
-	baseURL *url.URL
-	client  *http.Client
-	logger  logr.Logger
+	baseURL     *url.URL
+	client      *http.Client
+	logger      logr.Logger
+	retryPolicy *RetryPolicy
+	cache       *ttlCache
+	rateLimiter *RateLimiter
+
+	// The http client is assembled from these once every option is applied,
+	// so the options could be given in any order.
+	httpClient *http.Client
+	transport  http.RoundTripper
+	userAgent  string
+	apiToken   string
+	serviceKey string
 
 	accountID string
 	zoneID    string
@@ -58,21 +74,16 @@ type client struct {
 
 type ClientOption func(c *client) error
 
-// WithAPIToken use oauth2 client with given token as static token source
-//
-// NOTE: When used, this option should be called before any options to avoid
-// overwrite existing http.Client
+// WithAPIToken authenticates the requests with given token as oauth2 static token source.
 func WithAPIToken(token string) ClientOption {
 	return func(c *client) error {
-		ctx := context.Background()
-		c.client = oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}))
+		c.apiToken = token
 		return nil
 	}
 }
 
 // WithOriginCert use origincert to populate accountID and zoneID.
-// This options also wrap the http.DefaultTransport to inject
-// X-Auth-User-Service-Key header
+// The requests are also authenticated by injecting X-Auth-User-Service-Key header.
 func WithOriginCert(origincert []byte) ClientOption {
 	return func(c *client) error {
 		cert, err := certutil.DecodeOriginCert(origincert)
@@ -80,16 +91,75 @@ func WithOriginCert(origincert []byte) ClientOption {
 			return err
 		}
 
-		client := c.client
 		c.zoneID = cert.ZoneID
 		c.accountID = cert.AccountID
-		c.client = &http.Client{
-			Transport: &ServiceKeyTransport{
-				Base:       client.Transport,
-				ServiceKey: cert.ServiceKey,
-			},
+		c.serviceKey = cert.ServiceKey
+		return nil
+	}
+}
+
+// WithBaseURL set the cloudflare api base url, e.g. to point the client to a local stand-in.
+func WithBaseURL(baseURL string) ClientOption {
+	return func(c *client) error {
+		u, err := url.Parse(baseURL)
+		if err != nil {
+			return err
 		}
 
+		c.baseURL = u
+		return nil
+	}
+}
+
+// WithHTTPClient use given http.Client, e.g. to set the request timeout. The given client
+// is not modified, the authentication is injected into a copy of it.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *client) error {
+		c.httpClient = httpClient
+		return nil
+	}
+}
+
+// WithTransport use given transport to make the requests, e.g. one going through an egress proxy
+// or trusting a custom CA. It takes precedence over the transport of WithHTTPClient.
+func WithTransport(transport http.RoundTripper) ClientOption {
+	return func(c *client) error {
+		c.transport = transport
+		return nil
+	}
+}
+
+// WithUserAgent set the User-Agent header of every request.
+func WithUserAgent(userAgent string) ClientOption {
+	return func(c *client) error {
+		c.userAgent = userAgent
+		return nil
+	}
+}
+
+// WithAccountID set the account the client operates on.
+// When used along with WithOriginCert, the latest one wins.
+func WithAccountID(accountID string) ClientOption {
+	return func(c *client) error {
+		c.accountID = accountID
+		return nil
+	}
+}
+
+// WithZoneID set the zone the client operates on.
+// When used along with WithOriginCert, the latest one wins.
+func WithZoneID(zoneID string) ClientOption {
+	return func(c *client) error {
+		c.zoneID = zoneID
+		return nil
+	}
+}
+
+// WithCacheTTL caches the zone lookups and the resources fetched by name for the ttl.
+// Only found resources are cached, and they are invalidated when changed through the client.
+func WithCacheTTL(ttl time.Duration) ClientOption {
+	return func(c *client) error {
+		c.cache = newTTLCache(ttl)
 		return nil
 	}
 }
@@ -108,8 +178,8 @@ func NewClient(opts ...ClientOption) (Client, error) {
 	}
 
 	c := &client{
-		client:  http.DefaultClient,
 		baseURL: baseURL,
+		cache:   newTTLCache(0),
 	}
 
 	for _, opt := range opts {
@@ -118,6 +188,8 @@ func NewClient(opts ...ClientOption) (Client, error) {
 		}
 	}
 
+	c.client = c.buildHTTPClient()
+
 	if c.logger == nil {
 		c.logger = zapr.NewLogger(zap.NewNop())
 	}
@@ -125,6 +197,40 @@ func NewClient(opts ...ClientOption) (Client, error) {
 	return c, nil
 }
 
+// buildHTTPClient wraps the base transport with the user agent and the authentication.
+func (c *client) buildHTTPClient() *http.Client {
+	httpClient := &http.Client{}
+	if c.httpClient != nil {
+		*httpClient = *c.httpClient
+	}
+
+	transport := http.DefaultTransport
+	switch {
+	case c.transport != nil:
+		transport = c.transport
+	case httpClient.Transport != nil:
+		transport = httpClient.Transport
+	}
+
+	if c.userAgent != "" {
+		transport = &UserAgentTransport{Base: transport, UserAgent: c.userAgent}
+	}
+
+	if c.apiToken != "" {
+		transport = &oauth2.Transport{
+			Base:   transport,
+			Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: c.apiToken}),
+		}
+	}
+
+	if c.serviceKey != "" {
+		transport = &ServiceKeyTransport{Base: transport, ServiceKey: c.serviceKey}
+	}
+
+	httpClient.Transport = transport
+	return httpClient
+}
+
 func (c *client) AccountID() string {
 	return c.accountID
 }
@@ -140,3 +246,19 @@ func (c *client) Tunnels() TunnelClient {
 func (c *client) Zones() ZoneClient {
 	return newZones(c)
 }
+
+func (c *client) DNSRecords() DNSRecordClient {
+	return newDNSRecords(c)
+}
+
+func (c *client) LoadBalancers() LoadBalancerClient {
+	return newLoadBalancers(c)
+}
+
+func (c *client) NetworkRoutes() NetworkRouteClient {
+	return newNetworkRoutes(c)
+}
+
+func (c *client) VirtualNetworks() VirtualNetworkClient {
+	return newVirtualNetworks(c)
+}
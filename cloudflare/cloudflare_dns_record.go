@@ -0,0 +1,160 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+const (
+	DNSRecordTypeCNAME = "CNAME"
+)
+
+type DNSRecordClient interface {
+	Get(ctx context.Context, zoneID, recordID string) (*DNSRecord, error)
+	List(ctx context.Context, zoneID string, opts *DNSRecordListOptions) ([]*DNSRecord, error)
+	Create(ctx context.Context, zoneID string, record *DNSRecord) (*DNSRecord, error)
+	Update(ctx context.Context, zoneID string, record *DNSRecord) (*DNSRecord, error)
+	Delete(ctx context.Context, zoneID, recordID string) error
+}
+
+type DNSRecord struct {
+	ID         string     `json:"id,omitempty"`
+	ZoneID     string     `json:"zone_id,omitempty"`
+	ZoneName   string     `json:"zone_name,omitempty"`
+	Type       string     `json:"type,omitempty"`
+	Name       string     `json:"name,omitempty"`
+	Content    string     `json:"content,omitempty"`
+	Proxied    *bool      `json:"proxied,omitempty"`
+	TTL        int        `json:"ttl,omitempty"`
+	Comment    string     `json:"comment,omitempty"`
+	Tags       []string   `json:"tags,omitempty"`
+	CreatedOn  *time.Time `json:"created_on,omitempty"`
+	ModifiedOn *time.Time `json:"modified_on,omitempty"`
+}
+
+type DNSRecordListOptions struct {
+	Type    string   `url:"type,omitempty"`
+	Name    string   `url:"name,omitempty"`
+	Content string   `url:"content,omitempty"`
+	Comment string   `url:"comment,omitempty"`
+	Tags    []string `url:"tag,omitempty"`
+	// Match is whether to match all (default) or at least one search parameter, "all" or "any".
+	Match string `url:"match,omitempty"`
+}
+
+type dnsRecords struct {
+	client *client
+}
+
+func newDNSRecords(c *client) *dnsRecords {
+	return &dnsRecords{
+		client: c,
+	}
+}
+
+// Get fetch a dns record.
+//
+// API reference: https://api.cloudflare.com/#dns-records-for-a-zone-dns-record-details
+func (s *dnsRecords) Get(ctx context.Context, zoneID, recordID string) (*DNSRecord, error) {
+	s.client.logger.V(1).Info("Getting dns record details", "zone-id", zoneID, "record-id", recordID)
+	record := &DNSRecord{}
+	err := NewRequest(s.client).
+		Verb(http.MethodGet).
+		ZonePrefix(zoneID).
+		Resource("dns_records").
+		ResourceID(recordID).
+		Do(ctx).
+		Into(record)
+	return record, err
+}
+
+// List retrieves all dns records of the zone, following every page of the result.
+//
+// API reference: https://api.cloudflare.com/#dns-records-for-a-zone-list-dns-records
+func (s *dnsRecords) List(ctx context.Context, zoneID string, opts *DNSRecordListOptions) ([]*DNSRecord, error) {
+	var recordList []*DNSRecord
+	if opts == nil {
+		opts = &DNSRecordListOptions{}
+	}
+
+	s.client.logger.V(1).Info("Retriving dns records", "zone-id", zoneID, "options", opts)
+	err := NewRequest(s.client).
+		Verb(http.MethodGet).
+		ZonePrefix(zoneID).
+		Resource("dns_records").
+		Param(opts).
+		DoPages(ctx, func(result RequestResult) error {
+			var page []*DNSRecord
+			if err := result.Into(&page); err != nil {
+				return err
+			}
+
+			recordList = append(recordList, page...)
+			return nil
+		})
+	return recordList, err
+}
+
+// Create creates a new dns record for the zone.
+//
+// API reference: https://api.cloudflare.com/#dns-records-for-a-zone-create-dns-record
+func (s *dnsRecords) Create(ctx context.Context, zoneID string, record *DNSRecord) (*DNSRecord, error) {
+	s.client.logger.V(1).Info("Creating dns record", "zone-id", zoneID, "type", record.Type, "name", record.Name)
+	created := &DNSRecord{}
+	err := NewRequest(s.client).
+		Verb(http.MethodPost).
+		ZonePrefix(zoneID).
+		Resource("dns_records").
+		Body(record).
+		Do(ctx).
+		Into(created)
+	return created, err
+}
+
+// Update overwrites a dns record identified by record.ID.
+//
+// API reference: https://api.cloudflare.com/#dns-records-for-a-zone-update-dns-record
+func (s *dnsRecords) Update(ctx context.Context, zoneID string, record *DNSRecord) (*DNSRecord, error) {
+	s.client.logger.V(1).Info("Updating dns record", "zone-id", zoneID, "record-id", record.ID)
+	updated := &DNSRecord{}
+	err := NewRequest(s.client).
+		Verb(http.MethodPut).
+		ZonePrefix(zoneID).
+		Resource("dns_records").
+		ResourceID(record.ID).
+		Body(record).
+		Do(ctx).
+		Into(updated)
+	return updated, err
+}
+
+// Delete removes a dns record.
+//
+// API reference: https://api.cloudflare.com/#dns-records-for-a-zone-delete-dns-record
+func (s *dnsRecords) Delete(ctx context.Context, zoneID, recordID string) error {
+	s.client.logger.V(1).Info("Deleting dns record", "zone-id", zoneID, "record-id", recordID)
+	return NewRequest(s.client).
+		Verb(http.MethodDelete).
+		ZonePrefix(zoneID).
+		Resource("dns_records").
+		ResourceID(recordID).
+		Do(ctx).
+		Error()
+}
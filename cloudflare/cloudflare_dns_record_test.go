@@ -0,0 +1,218 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestDNSRecords_List(t *testing.T) {
+	pages := [][]*DNSRecord{
+		{{ID: "record-a", Name: "a.example.com"}, {ID: "record-b", Name: "b.example.com"}},
+		{{ID: "record-c", Name: "c.example.com"}},
+	}
+	tests := []struct {
+		name      string
+		opts      *DNSRecordListOptions
+		wantQuery url.Values
+	}{
+		{
+			name:      "no options",
+			wantQuery: url.Values{},
+		},
+		{
+			name: "filters",
+			opts: &DNSRecordListOptions{
+				Type:    DNSRecordTypeCNAME,
+				Name:    "foo.example.com",
+				Content: "f70ff985-a4ef-4643-bbbc-4a0ed4fc8415.cfargotunnel.com",
+				Comment: "managed",
+				Tags:    []string{"owner:foo", "team:a"},
+				Match:   "any",
+			},
+			wantQuery: url.Values{
+				"type":    {"CNAME"},
+				"name":    {"foo.example.com"},
+				"content": {"f70ff985-a4ef-4643-bbbc-4a0ed4fc8415.cfargotunnel.com"},
+				"comment": {"managed"},
+				"tag":     {"owner:foo", "team:a"},
+				"match":   {"any"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet || r.URL.Path != "/client/v4/zones/zone-id/dns_records" {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+				}
+
+				query := r.URL.Query()
+				page, _ := strconv.Atoi(query.Get("page"))
+				query.Del("page")
+				query.Del("per_page")
+				if !reflect.DeepEqual(query, tt.wantQuery) {
+					t.Errorf("query = %v, want %v", query, tt.wantQuery)
+				}
+
+				writeTestResponse(w, pages[page-1], ResultInfo{
+					Page:       page,
+					TotalPages: len(pages),
+					Count:      len(pages[page-1]),
+				})
+			}))
+
+			got, err := c.DNSRecords().List(context.Background(), "zone-id", tt.opts)
+			if err != nil {
+				t.Fatalf("dnsRecords.List() error = %v", err)
+			}
+
+			var gotIDs []string
+			for _, record := range got {
+				gotIDs = append(gotIDs, record.ID)
+			}
+
+			if want := []string{"record-a", "record-b", "record-c"}; !reflect.DeepEqual(gotIDs, want) {
+				t.Errorf("dnsRecords.List() = %v, want %v", gotIDs, want)
+			}
+		})
+	}
+}
+
+func TestDNSRecords_Get(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/client/v4/zones/zone-id/dns_records/record-id" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		writeTestResponse(w, &DNSRecord{ID: "record-id", Type: DNSRecordTypeCNAME, Name: "foo.example.com"}, ResultInfo{})
+	}))
+
+	got, err := c.DNSRecords().Get(context.Background(), "zone-id", "record-id")
+	if err != nil {
+		t.Fatalf("dnsRecords.Get() error = %v", err)
+	}
+
+	if want := (&DNSRecord{ID: "record-id", Type: DNSRecordTypeCNAME, Name: "foo.example.com"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("dnsRecords.Get() = %v, want %v", got, want)
+	}
+}
+
+func TestDNSRecords_Create(t *testing.T) {
+	proxied := true
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/client/v4/zones/zone-id/dns_records" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		var got map[string]interface{}
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Fatal(err)
+		}
+
+		want := map[string]interface{}{
+			"type":    "CNAME",
+			"name":    "foo.example.com",
+			"content": "f70ff985-a4ef-4643-bbbc-4a0ed4fc8415.cfargotunnel.com",
+			"proxied": true,
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("body = %v, want %v", got, want)
+		}
+
+		writeTestResponse(w, &DNSRecord{ID: "record-id", Type: DNSRecordTypeCNAME, Name: "foo.example.com"}, ResultInfo{})
+	}))
+
+	got, err := c.DNSRecords().Create(context.Background(), "zone-id", &DNSRecord{
+		Type:    DNSRecordTypeCNAME,
+		Name:    "foo.example.com",
+		Content: "f70ff985-a4ef-4643-bbbc-4a0ed4fc8415.cfargotunnel.com",
+		Proxied: &proxied,
+	})
+	if err != nil {
+		t.Fatalf("dnsRecords.Create() error = %v", err)
+	}
+
+	if got.ID != "record-id" {
+		t.Errorf("dnsRecords.Create() = %v, want record-id", got.ID)
+	}
+}
+
+func TestDNSRecords_Update(t *testing.T) {
+	proxied := true
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != "/client/v4/zones/zone-id/dns_records/record-id" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		var got map[string]interface{}
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Fatal(err)
+		}
+
+		want := map[string]interface{}{
+			"id":      "record-id",
+			"type":    "CNAME",
+			"name":    "foo.example.com",
+			"content": "bar.example.com",
+			"proxied": true,
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("body = %v, want %v", got, want)
+		}
+
+		writeTestResponse(w, &DNSRecord{ID: "record-id", Type: DNSRecordTypeCNAME, Name: "foo.example.com", Content: "bar.example.com"}, ResultInfo{})
+	}))
+
+	got, err := c.DNSRecords().Update(context.Background(), "zone-id", &DNSRecord{
+		ID:      "record-id",
+		Type:    DNSRecordTypeCNAME,
+		Name:    "foo.example.com",
+		Content: "bar.example.com",
+		Proxied: &proxied,
+	})
+	if err != nil {
+		t.Fatalf("dnsRecords.Update() error = %v", err)
+	}
+
+	if got.Content != "bar.example.com" {
+		t.Errorf("dnsRecords.Update() = %v, want bar.example.com", got.Content)
+	}
+}
+
+func TestDNSRecords_Delete(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete || r.URL.Path != "/client/v4/zones/zone-id/dns_records/record-id" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		writeTestResponse(w, &DNSRecord{ID: "record-id"}, ResultInfo{})
+	}))
+
+	if err := c.DNSRecords().Delete(context.Background(), "zone-id", "record-id"); err != nil {
+		t.Errorf("dnsRecords.Delete() error = %v", err)
+	}
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+type LoadBalancerClient interface {
+	Get(ctx context.Context, zoneID, loadBalancerID string) (*LoadBalancer, error)
+	List(ctx context.Context, zoneID string) ([]*LoadBalancer, error)
+
+	GetPool(ctx context.Context, poolID string) (*LoadBalancerPool, error)
+	GetPoolByName(ctx context.Context, name string) (*LoadBalancerPool, error)
+	ListPools(ctx context.Context) ([]*LoadBalancerPool, error)
+	UpdatePoolOrigins(ctx context.Context, poolID string, origins []LoadBalancerOrigin) (*LoadBalancerPool, error)
+}
+
+type LoadBalancer struct {
+	ID           string   `json:"id,omitempty"`
+	Name         string   `json:"name"`
+	Description  string   `json:"description,omitempty"`
+	Enabled      *bool    `json:"enabled,omitempty"`
+	Proxied      bool     `json:"proxied"`
+	DefaultPools []string `json:"default_pools"`
+	FallbackPool string   `json:"fallback_pool"`
+}
+
+type LoadBalancerPool struct {
+	ID          string               `json:"id,omitempty"`
+	Name        string               `json:"name"`
+	Description string               `json:"description,omitempty"`
+	Enabled     bool                 `json:"enabled"`
+	Monitor     string               `json:"monitor,omitempty"`
+	Origins     []LoadBalancerOrigin `json:"origins"`
+}
+
+type LoadBalancerOrigin struct {
+	Name    string              `json:"name"`
+	Address string              `json:"address"`
+	Enabled bool                `json:"enabled"`
+	Weight  float64             `json:"weight,omitempty"`
+	Header  map[string][]string `json:"header,omitempty"`
+
+	// raw holds the origin as returned by the API, so the origin settings
+	// not modelled above are sent back unchanged.
+	raw map[string]json.RawMessage
+}
+
+type loadBalancerOrigin LoadBalancerOrigin
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (o *LoadBalancerOrigin) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, (*loadBalancerOrigin)(o)); err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, &o.raw)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (o LoadBalancerOrigin) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(loadBalancerOrigin(o))
+	if err != nil || len(o.raw) == 0 {
+		return data, err
+	}
+
+	fields := make(map[string]json.RawMessage, len(o.raw))
+	for key, value := range o.raw {
+		fields[key] = value
+	}
+
+	// Modelled fields omitted as empty are dropped rather than sent back with their previous value.
+	for _, key := range []string{"weight", "header"} {
+		delete(fields, key)
+	}
+
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(fields)
+}
+
+type loadBalancers struct {
+	client *client
+}
+
+func newLoadBalancers(c *client) *loadBalancers {
+	return &loadBalancers{
+		client: c,
+	}
+}
+
+// Get fetch a load balancer.
+//
+// API reference: https://api.cloudflare.com/#load-balancers-load-balancer-details
+func (s *loadBalancers) Get(ctx context.Context, zoneID, loadBalancerID string) (*LoadBalancer, error) {
+	s.client.logger.V(1).Info("Getting load balancer details", "zone-id", zoneID, "load-balancer-id", loadBalancerID)
+	lb := &LoadBalancer{}
+	err := NewRequest(s.client).
+		Verb(http.MethodGet).
+		ZonePrefix(zoneID).
+		Resource("load_balancers").
+		ResourceID(loadBalancerID).
+		Do(ctx).
+		Into(lb)
+	return lb, err
+}
+
+// List retrieves all load balancers of the zone.
+//
+// API reference: https://api.cloudflare.com/#load-balancers-list-load-balancers
+func (s *loadBalancers) List(ctx context.Context, zoneID string) ([]*LoadBalancer, error) {
+	var lbList []*LoadBalancer
+	s.client.logger.V(1).Info("Retriving load balancers", "zone-id", zoneID)
+	err := NewRequest(s.client).
+		Verb(http.MethodGet).
+		ZonePrefix(zoneID).
+		Resource("load_balancers").
+		DoPages(ctx, func(result RequestResult) error {
+			var page []*LoadBalancer
+			if err := result.Into(&page); err != nil {
+				return err
+			}
+
+			lbList = append(lbList, page...)
+			return nil
+		})
+	return lbList, err
+}
+
+// GetPool fetch a load balancer pool.
+//
+// API reference: https://api.cloudflare.com/#account-load-balancer-pools-pool-details
+func (s *loadBalancers) GetPool(ctx context.Context, poolID string) (*LoadBalancerPool, error) {
+	s.client.logger.V(1).Info("Getting load balancer pool details", "pool-id", poolID)
+	pool := &LoadBalancerPool{}
+	err := NewRequest(s.client).
+		Verb(http.MethodGet).
+		AccountPrefix(s.client.accountID).
+		Resource("load_balancers/pools").
+		ResourceID(poolID).
+		Do(ctx).
+		Into(pool)
+	return pool, err
+}
+
+// GetPoolByName fetch a load balancer pool by name.
+func (s *loadBalancers) GetPoolByName(ctx context.Context, name string) (*LoadBalancerPool, error) {
+	s.client.logger.V(1).Info("Getting load balancer pool details by name", "name", name)
+	poolList, err := s.ListPools(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pool := range poolList {
+		if pool.Name == name {
+			return pool, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+// ListPools retrieves all load balancer pools of the account.
+//
+// API reference: https://api.cloudflare.com/#account-load-balancer-pools-list-pools
+func (s *loadBalancers) ListPools(ctx context.Context) ([]*LoadBalancerPool, error) {
+	var poolList []*LoadBalancerPool
+	s.client.logger.V(1).Info("Retriving load balancer pools")
+	err := NewRequest(s.client).
+		Verb(http.MethodGet).
+		AccountPrefix(s.client.accountID).
+		Resource("load_balancers/pools").
+		DoPages(ctx, func(result RequestResult) error {
+			var page []*LoadBalancerPool
+			if err := result.Into(&page); err != nil {
+				return err
+			}
+
+			poolList = append(poolList, page...)
+			return nil
+		})
+	return poolList, err
+}
+
+// UpdatePoolOrigins replaces the origins of a load balancer pool, leaving the other
+// settings of the pool untouched.
+//
+// API reference: https://api.cloudflare.com/#account-load-balancer-pools-patch-pool
+func (s *loadBalancers) UpdatePoolOrigins(ctx context.Context, poolID string, origins []LoadBalancerOrigin) (*LoadBalancerPool, error) {
+	s.client.logger.V(1).Info("Updating load balancer pool origins", "pool-id", poolID)
+	updated := &LoadBalancerPool{}
+	err := NewRequest(s.client).
+		Verb(http.MethodPatch).
+		AccountPrefix(s.client.accountID).
+		Resource("load_balancers/pools").
+		ResourceID(poolID).
+		Body(map[string]interface{}{"origins": origins}).
+		Do(ctx).
+		Into(updated)
+	return updated, err
+}
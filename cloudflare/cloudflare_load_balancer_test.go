@@ -0,0 +1,166 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestLoadBalancers_Get(t *testing.T) {
+	enabled := true
+	lb := &LoadBalancer{
+		ID:           "lb-id",
+		Name:         "www.example.com",
+		Enabled:      &enabled,
+		Proxied:      true,
+		DefaultPools: []string{"pool-a"},
+		FallbackPool: "pool-a",
+	}
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/client/v4/zones/zone-id/load_balancers/lb-id" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		writeTestResponse(w, lb, ResultInfo{})
+	}))
+
+	got, err := c.LoadBalancers().Get(context.Background(), "zone-id", "lb-id")
+	if err != nil {
+		t.Fatalf("loadBalancers.Get() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(got, lb) {
+		t.Errorf("loadBalancers.Get() = %v, want %v", got, lb)
+	}
+}
+
+func TestLoadBalancers_GetPoolByName(t *testing.T) {
+	poolList := []*LoadBalancerPool{
+		{ID: "pool-a", Name: "pool-a", Enabled: true},
+		{ID: "pool-b", Name: "pool-b", Enabled: true},
+	}
+	tests := []struct {
+		name    string
+		pool    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "found",
+			pool: "pool-b",
+			want: "pool-b",
+		},
+		{
+			name:    "not found",
+			pool:    "pool-c",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet || r.URL.Path != "/client/v4/accounts/account-id/load_balancers/pools" {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+				}
+
+				writeTestResponse(w, poolList, ResultInfo{})
+			}))
+			c.accountID = "account-id"
+
+			got, err := c.LoadBalancers().GetPoolByName(context.Background(), tt.pool)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadBalancers.GetPoolByName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				if !IsNotFound(err) {
+					t.Errorf("loadBalancers.GetPoolByName() error = %v, want not found", err)
+				}
+
+				return
+			}
+
+			if got.ID != tt.want {
+				t.Errorf("loadBalancers.GetPoolByName() = %v, want %v", got.ID, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadBalancers_UpdatePoolOrigins(t *testing.T) {
+	const pool = `{
+		"id": "pool-id",
+		"name": "pool",
+		"enabled": true,
+		"minimum_origins": 1,
+		"notification_email": "ops@example.com",
+		"origins": [
+			{"name": "a", "address": "a.cfargotunnel.com", "enabled": true, "weight": 0.5, "virtual_network_id": "vnet-id"},
+			{"name": "b", "address": "b.cfargotunnel.com", "enabled": true, "weight": 0.5}
+		]
+	}`
+	var gotBody map[string][]map[string]interface{}
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/client/v4/accounts/account-id/load_balancers/pools/pool-id" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			writeTestResponse(w, json.RawMessage(pool), ResultInfo{})
+		case http.MethodPatch:
+			body, _ := ioutil.ReadAll(r.Body)
+			if err := json.Unmarshal(body, &gotBody); err != nil {
+				t.Errorf("unexpected body %s: %v", body, err)
+			}
+
+			writeTestResponse(w, json.RawMessage(pool), ResultInfo{})
+		default:
+			t.Errorf("unexpected method %s", r.Method)
+		}
+	}))
+	c.accountID = "account-id"
+
+	got, err := c.LoadBalancers().GetPool(context.Background(), "pool-id")
+	if err != nil {
+		t.Fatalf("loadBalancers.GetPool() error = %v", err)
+	}
+
+	if got.Name != "pool" || len(got.Origins) != 2 {
+		t.Fatalf("loadBalancers.GetPool() = %v", got)
+	}
+
+	got.Origins[0].Enabled = false
+	got.Origins[0].Weight = 0
+	if _, err := c.LoadBalancers().UpdatePoolOrigins(context.Background(), got.ID, got.Origins[:1]); err != nil {
+		t.Fatalf("loadBalancers.UpdatePoolOrigins() error = %v", err)
+	}
+
+	want := map[string][]map[string]interface{}{
+		"origins": {
+			{"name": "a", "address": "a.cfargotunnel.com", "enabled": false, "virtual_network_id": "vnet-id"},
+		},
+	}
+	if !reflect.DeepEqual(gotBody, want) {
+		t.Errorf("loadBalancers.UpdatePoolOrigins() body = %v, want %v", gotBody, want)
+	}
+}
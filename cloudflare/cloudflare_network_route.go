@@ -0,0 +1,122 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type NetworkRouteClient interface {
+	List(ctx context.Context, opts *NetworkRouteListOptions) ([]*NetworkRoute, error)
+	Create(ctx context.Context, route *NetworkRoute) (*NetworkRoute, error)
+	Delete(ctx context.Context, routeID string) error
+}
+
+// NetworkRoute is a private network route of a tunnel.
+type NetworkRoute struct {
+	ID               string     `json:"id,omitempty"`
+	Network          string     `json:"network"`
+	TunnelID         uuid.UUID  `json:"tunnel_id"`
+	TunnelName       string     `json:"tunnel_name,omitempty"`
+	Comment          string     `json:"comment,omitempty"`
+	VirtualNetworkID string     `json:"virtual_network_id,omitempty"`
+	CreatedAt        *time.Time `json:"created_at,omitempty"`
+	DeletedAt        *time.Time `json:"deleted_at,omitempty"`
+}
+
+type NetworkRouteListOptions struct {
+	TunnelID         string `url:"tunnel_id,omitempty"`
+	Comment          string `url:"comment,omitempty"`
+	VirtualNetworkID string `url:"virtual_network_id,omitempty"`
+	// NetworkSubset filters the routes whose network is a subset of, or equal to, the given CIDR.
+	NetworkSubset string `url:"network_subset,omitempty"`
+	// NetworkSuperset filters the routes whose network is a superset of, or equal to, the given CIDR.
+	NetworkSuperset string `url:"network_superset,omitempty"`
+	// IsDeleted includes only the deleted routes when true, and only the existing routes when false.
+	IsDeleted *bool `url:"is_deleted,omitempty"`
+}
+
+type networkRoutes struct {
+	client *client
+}
+
+func newNetworkRoutes(c *client) *networkRoutes {
+	return &networkRoutes{
+		client: c,
+	}
+}
+
+// List retrieves the private network routes of the account.
+//
+// API reference: https://api.cloudflare.com/#tunnel-route-list-tunnel-routes
+func (s *networkRoutes) List(ctx context.Context, opts *NetworkRouteListOptions) ([]*NetworkRoute, error) {
+	var routeList []*NetworkRoute
+	if opts == nil {
+		opts = &NetworkRouteListOptions{}
+	}
+
+	s.client.logger.V(1).Info("Retriving network routes", "options", opts)
+	err := NewRequest(s.client).
+		Verb(http.MethodGet).
+		AccountPrefix(s.client.accountID).
+		Resource("teamnet/routes").
+		Param(opts).
+		DoPages(ctx, func(result RequestResult) error {
+			var page []*NetworkRoute
+			if err := result.Into(&page); err != nil {
+				return err
+			}
+
+			routeList = append(routeList, page...)
+			return nil
+		})
+	return routeList, err
+}
+
+// Create routes a private network through a tunnel.
+//
+// API reference: https://api.cloudflare.com/#tunnel-route-create-tunnel-route
+func (s *networkRoutes) Create(ctx context.Context, route *NetworkRoute) (*NetworkRoute, error) {
+	s.client.logger.V(1).Info("Creating network route", "network", route.Network, "tunnel-id", route.TunnelID.String())
+	created := &NetworkRoute{}
+	err := NewRequest(s.client).
+		Verb(http.MethodPost).
+		AccountPrefix(s.client.accountID).
+		Resource("teamnet/routes").
+		Body(route).
+		Do(ctx).
+		Into(created)
+	return created, err
+}
+
+// Delete removes a private network route.
+//
+// API reference: https://api.cloudflare.com/#tunnel-route-delete-tunnel-route
+func (s *networkRoutes) Delete(ctx context.Context, routeID string) error {
+	s.client.logger.V(1).Info("Deleting network route", "route-id", routeID)
+	return NewRequest(s.client).
+		Verb(http.MethodDelete).
+		AccountPrefix(s.client.accountID).
+		Resource("teamnet/routes").
+		ResourceID(routeID).
+		Do(ctx).
+		Error()
+}
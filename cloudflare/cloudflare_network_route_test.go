@@ -0,0 +1,145 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestNetworkRoutes_List(t *testing.T) {
+	isDeleted := false
+	tests := []struct {
+		name      string
+		opts      *NetworkRouteListOptions
+		wantQuery url.Values
+	}{
+		{
+			name:      "no options",
+			wantQuery: url.Values{},
+		},
+		{
+			name: "filters",
+			opts: &NetworkRouteListOptions{
+				TunnelID:         "f70ff985-a4ef-4643-bbbc-4a0ed4fc8415",
+				VirtualNetworkID: "vnet-id",
+				NetworkSubset:    "10.0.0.0/8",
+				NetworkSuperset:  "10.0.0.0/8",
+				IsDeleted:        &isDeleted,
+			},
+			wantQuery: url.Values{
+				"tunnel_id":          {"f70ff985-a4ef-4643-bbbc-4a0ed4fc8415"},
+				"virtual_network_id": {"vnet-id"},
+				"network_subset":     {"10.0.0.0/8"},
+				"network_superset":   {"10.0.0.0/8"},
+				"is_deleted":         {"false"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet || r.URL.Path != "/client/v4/accounts/account-id/teamnet/routes" {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+				}
+
+				query := r.URL.Query()
+				query.Del("page")
+				query.Del("per_page")
+				if !reflect.DeepEqual(query, tt.wantQuery) {
+					t.Errorf("query = %v, want %v", query, tt.wantQuery)
+				}
+
+				writeTestResponse(w, []*NetworkRoute{{ID: "route-id", Network: "10.0.0.0/8"}}, ResultInfo{})
+			}))
+			c.accountID = "account-id"
+
+			got, err := c.NetworkRoutes().List(context.Background(), tt.opts)
+			if err != nil {
+				t.Fatalf("networkRoutes.List() error = %v", err)
+			}
+
+			if len(got) != 1 || got[0].ID != "route-id" {
+				t.Errorf("networkRoutes.List() = %v", got)
+			}
+		})
+	}
+}
+
+func TestNetworkRoutes_Create(t *testing.T) {
+	tunnelID := uuid.MustParse("f70ff985-a4ef-4643-bbbc-4a0ed4fc8415")
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/client/v4/accounts/account-id/teamnet/routes" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		var got map[string]interface{}
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Fatal(err)
+		}
+
+		want := map[string]interface{}{
+			"network":            "10.0.0.0/8",
+			"tunnel_id":          tunnelID.String(),
+			"comment":            "cluster networks",
+			"virtual_network_id": "vnet-id",
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("body = %v, want %v", got, want)
+		}
+
+		writeTestResponse(w, &NetworkRoute{ID: "route-id", Network: "10.0.0.0/8", TunnelID: tunnelID}, ResultInfo{})
+	}))
+	c.accountID = "account-id"
+
+	got, err := c.NetworkRoutes().Create(context.Background(), &NetworkRoute{
+		Network:          "10.0.0.0/8",
+		TunnelID:         tunnelID,
+		Comment:          "cluster networks",
+		VirtualNetworkID: "vnet-id",
+	})
+	if err != nil {
+		t.Fatalf("networkRoutes.Create() error = %v", err)
+	}
+
+	if got.ID != "route-id" {
+		t.Errorf("networkRoutes.Create() = %v, want route-id", got.ID)
+	}
+}
+
+func TestNetworkRoutes_Delete(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete || r.URL.Path != "/client/v4/accounts/account-id/teamnet/routes/route-id" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		writeTestResponse(w, &NetworkRoute{ID: "route-id"}, ResultInfo{})
+	}))
+	c.accountID = "account-id"
+
+	if err := c.NetworkRoutes().Delete(context.Background(), "route-id"); err != nil {
+		t.Errorf("networkRoutes.Delete() error = %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type countingTransport struct {
+	base  http.RoundTripper
+	count int
+}
+
+func (t *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.count++
+	return t.base.RoundTrip(r)
+}
+
+func TestNewClient(t *testing.T) {
+	var gotAuthorization, gotUserAgent, gotPath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuthorization = r.Header.Get("Authorization")
+		gotUserAgent = r.Header.Get("User-Agent")
+		gotPath = r.URL.Path
+		writeTestResponse(w, nil, ResultInfo{})
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name          string
+		opts          func(transport http.RoundTripper) []ClientOption
+		wantTransport bool
+	}{
+		{
+			name: "api token first",
+			opts: func(transport http.RoundTripper) []ClientOption {
+				return []ClientOption{
+					WithAPIToken("my-token"),
+					WithUserAgent("my-agent"),
+					WithHTTPClient(&http.Client{Transport: transport}),
+					WithBaseURL(srv.URL + "/client/v4"),
+				}
+			},
+			wantTransport: true,
+		},
+		{
+			name: "http client first",
+			opts: func(transport http.RoundTripper) []ClientOption {
+				return []ClientOption{
+					WithBaseURL(srv.URL + "/client/v4"),
+					WithHTTPClient(&http.Client{Transport: transport}),
+					WithUserAgent("my-agent"),
+					WithAPIToken("my-token"),
+				}
+			},
+			wantTransport: true,
+		},
+		{
+			name: "transport takes precedence over http client",
+			opts: func(transport http.RoundTripper) []ClientOption {
+				return []ClientOption{
+					WithTransport(http.DefaultTransport),
+					WithHTTPClient(&http.Client{Transport: transport}),
+					WithAPIToken("my-token"),
+					WithUserAgent("my-agent"),
+					WithBaseURL(srv.URL + "/client/v4"),
+				}
+			},
+			wantTransport: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &countingTransport{base: http.DefaultTransport}
+			c, err := NewClient(tt.opts(transport)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := NewRequest(c.(*client)).Verb(http.MethodGet).Resource("items").Do(context.Background()).Error(); err != nil {
+				t.Fatal(err)
+			}
+
+			if gotAuthorization != "Bearer my-token" || gotUserAgent != "my-agent" || gotPath != "/client/v4/items" {
+				t.Errorf("NewClient() request authorization = %q, user agent = %q, path = %q", gotAuthorization, gotUserAgent, gotPath)
+			}
+
+			if used := transport.count != 0; used != tt.wantTransport {
+				t.Errorf("NewClient() transport used = %v, want %v", used, tt.wantTransport)
+			}
+		})
+	}
+}
@@ -19,6 +19,7 @@ package cloudflare
 import (
 	"context"
 	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"net/http"
 	"time"
@@ -26,13 +27,27 @@ import (
 	"github.com/google/uuid"
 )
 
+// TunnelDomain is the domain of tunnel hostname. A DNS record routed to a tunnel
+// is a CNAME record pointing to <tunnel-id>.cfargotunnel.com
+const TunnelDomain = "cfargotunnel.com"
+
+// TunnelHostname returns the hostname of given tunnel id which should be the
+// content of DNS CNAME record routed to the tunnel.
+func TunnelHostname(tunnelID uuid.UUID) string {
+	return tunnelID.String() + "." + TunnelDomain
+}
+
 type TunnelClient interface {
 	Get(ctx context.Context, tunnelID uuid.UUID) (*Tunnel, error)
 	GetByName(ctx context.Context, name string) (*Tunnel, error)
 	List(ctx context.Context, opts *TunnelListOptions) ([]*Tunnel, error)
 	Create(ctx context.Context, name string) (*Tunnel, error)
 	Delete(ctx context.Context, tunnelID uuid.UUID) error
-	Route(ctx context.Context, tunnelID uuid.UUID, route TunnelRoute) error
+	Route(ctx context.Context, zoneID string, tunnelID uuid.UUID, route TunnelRoute) error
+	Connections(ctx context.Context, tunnelID uuid.UUID) ([]*TunnelConnector, error)
+	CleanupConnections(ctx context.Context, tunnelID uuid.UUID) error
+	UpdateConfiguration(ctx context.Context, tunnelID uuid.UUID, config *TunnelConfiguration) error
+	Token(ctx context.Context, tunnelID uuid.UUID) (string, error)
 }
 
 type TunnelCredentials struct {
@@ -42,6 +57,30 @@ type TunnelCredentials struct {
 	TunnelName   string
 }
 
+// ParseTunnelToken returns the credentials of the tunnel token. The token is the
+// base64 encoded JSON of the credentials with cloudflared short keys, it does not hold the tunnel name.
+func ParseTunnelToken(token string) (*TunnelCredentials, error) {
+	b, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed := struct {
+		AccountTag   string    `json:"a"`
+		TunnelSecret []byte    `json:"s"`
+		TunnelID     uuid.UUID `json:"t"`
+	}{}
+	if err := json.Unmarshal(b, &parsed); err != nil {
+		return nil, err
+	}
+
+	return &TunnelCredentials{
+		AccountTag:   parsed.AccountTag,
+		TunnelSecret: parsed.TunnelSecret,
+		TunnelID:     parsed.TunnelID,
+	}, nil
+}
+
 type Tunnel struct {
 	ID              uuid.UUID          `json:"id"`
 	Name            string             `json:"name"`
@@ -50,6 +89,58 @@ type Tunnel struct {
 	CredentialsFile *TunnelCredentials `json:"credentials_file,omitempty"`
 }
 
+// TunnelConnector is a cloudflared instance running the tunnel.
+type TunnelConnector struct {
+	ID          uuid.UUID          `json:"id"`
+	Features    []string           `json:"features"`
+	Version     string             `json:"version"`
+	Arch        string             `json:"arch"`
+	RunAt       time.Time          `json:"run_at"`
+	Connections []TunnelConnection `json:"conns"`
+}
+
+// TunnelConnection is a connection between a tunnel connector and the cloudflare edge.
+type TunnelConnection struct {
+	ID                 uuid.UUID `json:"id"`
+	ColoName           string    `json:"colo_name"`
+	IsPendingReconnect bool      `json:"is_pending_reconnect"`
+	OriginIP           string    `json:"origin_ip"`
+	OpenedAt           time.Time `json:"opened_at"`
+}
+
+// TunnelConfiguration is the configuration of a remotely managed tunnel, served
+// by cloudflare to the cloudflared connectors running with --token.
+type TunnelConfiguration struct {
+	Ingress       []TunnelIngressRule  `json:"ingress"`
+	OriginRequest *TunnelOriginRequest `json:"originRequest,omitempty"`
+	WarpRouting   *TunnelWarpRouting   `json:"warp-routing,omitempty"`
+}
+
+type TunnelIngressRule struct {
+	Hostname      string               `json:"hostname,omitempty"`
+	Path          string               `json:"path,omitempty"`
+	Service       string               `json:"service"`
+	OriginRequest *TunnelOriginRequest `json:"originRequest,omitempty"`
+}
+
+// TunnelOriginRequest is the origin configurations of a remotely managed tunnel.
+// Unlike the cloudflared config file, the timeouts are in seconds.
+type TunnelOriginRequest struct {
+	ConnectTimeout         int    `json:"connectTimeout,omitempty"`
+	TLSTimeout             int    `json:"tlsTimeout,omitempty"`
+	TCPKeepAlive           int    `json:"tcpKeepAlive,omitempty"`
+	KeepAliveConnections   int    `json:"keepAliveConnections,omitempty"`
+	KeepAliveTimeout       int    `json:"keepAliveTimeout,omitempty"`
+	DisableChunkedEncoding bool   `json:"disableChunkedEncoding,omitempty"`
+	HTTPHostHeader         string `json:"httpHostHeader,omitempty"`
+	NoTLSVerify            bool   `json:"noTLSVerify,omitempty"`
+	OriginServerName       string `json:"originServerName,omitempty"`
+}
+
+type TunnelWarpRouting struct {
+	Enabled bool `json:"enabled"`
+}
+
 type TunnelListOptions struct {
 	UUID      string `url:"uuid,omitempty"`
 	Name      string `url:"name,omitempty"`
@@ -116,10 +207,16 @@ func newTunnels(c *client) *tunnels {
 	}
 }
 
-// Get fetch a tunnel by name.
+// Get fetch a tunnel by ID.
 //
 // API reference: https://api.cloudflare.com/#argo-tunnel-get-argo-tunnel
 func (s *tunnels) Get(ctx context.Context, tunnelID uuid.UUID) (*Tunnel, error) {
+	cacheKey := "tunnel-id/" + tunnelID.String()
+	if cached, ok := s.client.cache.Get(cacheKey); ok {
+		tunnel := *cached.(*Tunnel)
+		return &tunnel, nil
+	}
+
 	s.client.logger.V(1).Info("Getting tunnel details", "tunnel-id", tunnelID.String())
 	tunnel := &Tunnel{}
 	err := NewRequest(s.client).
@@ -130,11 +227,23 @@ func (s *tunnels) Get(ctx context.Context, tunnelID uuid.UUID) (*Tunnel, error)
 		Header("Accept", "application/json;version=1").
 		Do(ctx).
 		Into(tunnel)
-	return tunnel, err
+	if err != nil {
+		return tunnel, err
+	}
+
+	cached := *tunnel
+	s.client.cache.Set(cacheKey, &cached)
+	return tunnel, nil
 }
 
 // Get fetch a tunnel by name.
 func (s *tunnels) GetByName(ctx context.Context, name string) (*Tunnel, error) {
+	cacheKey := "tunnel/" + name
+	if cached, ok := s.client.cache.Get(cacheKey); ok {
+		tunnel := *cached.(*Tunnel)
+		return &tunnel, nil
+	}
+
 	s.client.logger.V(1).Info("Getting tunnel details by name", "name", name)
 	tunnelList, err := s.List(ctx, &TunnelListOptions{
 		Name: name,
@@ -147,13 +256,15 @@ func (s *tunnels) GetByName(ctx context.Context, name string) (*Tunnel, error) {
 	case 0:
 		return nil, ErrNotFound
 	case 1:
+		cached := *tunnelList[0]
+		s.client.cache.Set(cacheKey, &cached)
 		return tunnelList[0], nil
 	default:
 		return nil, ErrUnexpectedMultipleTunnel
 	}
 }
 
-// List retrieves all tunnels.
+// List retrieves all tunnels, following every page of the result.
 //
 // API reference: https://api.cloudflare.com/#argo-tunnel-list-argo-tunnels
 func (s *tunnels) List(ctx context.Context, opts *TunnelListOptions) ([]*Tunnel, error) {
@@ -169,8 +280,15 @@ func (s *tunnels) List(ctx context.Context, opts *TunnelListOptions) ([]*Tunnel,
 		Resource("tunnels").
 		Header("Accept", "application/json;version=1").
 		Param(opts).
-		Do(ctx).
-		Into(&tunnelList)
+		DoPages(ctx, func(result RequestResult) error {
+			var page []*Tunnel
+			if err := result.Into(&page); err != nil {
+				return err
+			}
+
+			tunnelList = append(tunnelList, page...)
+			return nil
+		})
 	return tunnelList, err
 }
 
@@ -206,6 +324,11 @@ func (s *tunnels) Create(ctx context.Context, name string) (*Tunnel, error) {
 // API reference: https://api.cloudflare.com/#argo-tunnel-delete-argo-tunnel
 func (s *tunnels) Delete(ctx context.Context, tunnelID uuid.UUID) error {
 	s.client.logger.V(1).Info("Deleting tunnel", "tunnel-id", tunnelID.String())
+	s.client.cache.DeleteFunc(func(_ string, value interface{}) bool {
+		tunnel, ok := value.(*Tunnel)
+		return ok && tunnel.ID == tunnelID
+	})
+
 	return NewRequest(s.client).
 		Verb(http.MethodDelete).
 		AccountPrefix(s.client.accountID).
@@ -216,11 +339,12 @@ func (s *tunnels) Delete(ctx context.Context, tunnelID uuid.UUID) error {
 		Error()
 }
 
-func (s *tunnels) Route(ctx context.Context, tunnelID uuid.UUID, route TunnelRoute) error {
-	s.client.logger.V(1).Info("Routing tunnel", "tunnel-id", tunnelID.String(), "type", route.Type())
+// Route routes the tunnel from a hostname of the zone, either by DNS record or Load Balancer.
+func (s *tunnels) Route(ctx context.Context, zoneID string, tunnelID uuid.UUID, route TunnelRoute) error {
+	s.client.logger.V(1).Info("Routing tunnel", "zone-id", zoneID, "tunnel-id", tunnelID.String(), "type", route.Type())
 	return NewRequest(s.client).
 		Verb(http.MethodPut).
-		ZonePrefix(s.client.zoneID).
+		ZonePrefix(zoneID).
 		Resource("tunnels").
 		ResourceID(tunnelID.String()).
 		SubPath("routes").
@@ -229,3 +353,78 @@ func (s *tunnels) Route(ctx context.Context, tunnelID uuid.UUID, route TunnelRou
 		Do(ctx).
 		Error()
 }
+
+// Connections fetch the connectors of a tunnel along with their connections to the cloudflare edge.
+//
+// API reference: https://api.cloudflare.com/#argo-tunnel-list-argo-tunnel-connections
+func (s *tunnels) Connections(ctx context.Context, tunnelID uuid.UUID) ([]*TunnelConnector, error) {
+	s.client.logger.V(1).Info("Getting tunnel connections", "tunnel-id", tunnelID.String())
+	var connectors []*TunnelConnector
+	err := NewRequest(s.client).
+		Verb(http.MethodGet).
+		AccountPrefix(s.client.accountID).
+		Resource("tunnels").
+		ResourceID(tunnelID.String()).
+		SubPath("connections").
+		Header("Accept", "application/json;version=1").
+		Do(ctx).
+		Into(&connectors)
+	return connectors, err
+}
+
+// CleanupConnections removes the stale connections of a tunnel.
+//
+// API reference: https://api.cloudflare.com/#argo-tunnel-clean-up-argo-tunnel-connections
+func (s *tunnels) CleanupConnections(ctx context.Context, tunnelID uuid.UUID) error {
+	s.client.logger.V(1).Info("Cleaning up tunnel connections", "tunnel-id", tunnelID.String())
+	return NewRequest(s.client).
+		Verb(http.MethodDelete).
+		AccountPrefix(s.client.accountID).
+		Resource("tunnels").
+		ResourceID(tunnelID.String()).
+		SubPath("connections").
+		Header("Accept", "application/json;version=1").
+		Do(ctx).
+		Error()
+}
+
+// UpdateConfiguration overwrites the configuration of a remotely managed tunnel.
+// The connectors pick up the new configuration without restarting.
+//
+// API reference: https://api.cloudflare.com/#cloudflare-tunnel-configuration-put-configuration
+func (s *tunnels) UpdateConfiguration(ctx context.Context, tunnelID uuid.UUID, config *TunnelConfiguration) error {
+	s.client.logger.V(1).Info("Updating tunnel configuration", "tunnel-id", tunnelID.String())
+	body := struct {
+		Config *TunnelConfiguration `json:"config"`
+	}{
+		Config: config,
+	}
+
+	return NewRequest(s.client).
+		Verb(http.MethodPut).
+		AccountPrefix(s.client.accountID).
+		Resource("cfd_tunnel").
+		ResourceID(tunnelID.String()).
+		SubPath("configurations").
+		Body(body).
+		Do(ctx).
+		Error()
+}
+
+// Token fetch the token to run a tunnel with cloudflared --token.
+// Unlike the origincert, the token only grants running this tunnel.
+//
+// API reference: https://api.cloudflare.com/#cloudflare-tunnel-get-cloudflare-tunnel-token
+func (s *tunnels) Token(ctx context.Context, tunnelID uuid.UUID) (string, error) {
+	s.client.logger.V(1).Info("Getting tunnel token", "tunnel-id", tunnelID.String())
+	var token string
+	err := NewRequest(s.client).
+		Verb(http.MethodGet).
+		AccountPrefix(s.client.accountID).
+		Resource("cfd_tunnel").
+		ResourceID(tunnelID.String()).
+		SubPath("token").
+		Do(ctx).
+		Into(&token)
+	return token, err
+}
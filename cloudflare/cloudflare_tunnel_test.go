@@ -0,0 +1,366 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestTunnels_Token(t *testing.T) {
+	tunnelID := uuid.MustParse("f70ff985-a4ef-4643-bbbc-4a0ed4fc8415")
+	tests := []struct {
+		name       string
+		statusCode int
+		want       string
+		wantErr    bool
+	}{
+		{
+			name:       "found",
+			statusCode: http.StatusOK,
+			want:       "eyJhIjoiYWNjb3VudC10YWcifQ==",
+		},
+		{
+			name:       "not found",
+			statusCode: http.StatusNotFound,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				wantPath := "/client/v4/accounts/account-id/cfd_tunnel/" + tunnelID.String() + "/token"
+				if r.Method != http.MethodGet || r.URL.Path != wantPath {
+					t.Errorf("request = %s %s, want GET %s", r.Method, r.URL.Path, wantPath)
+				}
+
+				w.WriteHeader(tt.statusCode)
+				writeTestResponse(w, tt.want, ResultInfo{})
+			}))
+			c.accountID = "account-id"
+
+			got, err := c.Tunnels().Token(context.Background(), tunnelID)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("tunnels.Token() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if got != tt.want {
+				t.Errorf("tunnels.Token() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTunnels_Connections(t *testing.T) {
+	tunnelID := uuid.MustParse("f70ff985-a4ef-4643-bbbc-4a0ed4fc8415")
+	connectorID := uuid.MustParse("1bedc50d-42b3-473c-b108-ff3d10c0d925")
+	connID := uuid.MustParse("c8b8b2f4-8a43-4a07-9e0b-0c6e9ac3a5b1")
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wantPath := "/client/v4/accounts/account-id/tunnels/" + tunnelID.String() + "/connections"
+		if r.Method != http.MethodGet || r.URL.Path != wantPath {
+			t.Errorf("request = %s %s, want GET %s", r.Method, r.URL.Path, wantPath)
+		}
+
+		_, _ = w.Write([]byte(`{"success":true,"errors":[],"messages":[],"result":[{
+			"id":"1bedc50d-42b3-473c-b108-ff3d10c0d925",
+			"features":["ha-origin"],
+			"version":"2022.2.0",
+			"arch":"linux_amd64",
+			"run_at":"2022-02-01T10:00:00Z",
+			"conns":[{
+				"id":"c8b8b2f4-8a43-4a07-9e0b-0c6e9ac3a5b1",
+				"colo_name":"SIN",
+				"is_pending_reconnect":false,
+				"origin_ip":"10.0.0.1",
+				"opened_at":"2022-02-01T10:00:05Z"
+			}]
+		}]}`))
+	}))
+	c.accountID = "account-id"
+
+	got, err := c.Tunnels().Connections(context.Background(), tunnelID)
+	if err != nil {
+		t.Fatalf("tunnels.Connections() error = %v", err)
+	}
+
+	want := []*TunnelConnector{
+		{
+			ID:       connectorID,
+			Features: []string{"ha-origin"},
+			Version:  "2022.2.0",
+			Arch:     "linux_amd64",
+			RunAt:    time.Date(2022, 2, 1, 10, 0, 0, 0, time.UTC),
+			Connections: []TunnelConnection{
+				{
+					ID:       connID,
+					ColoName: "SIN",
+					OriginIP: "10.0.0.1",
+					OpenedAt: time.Date(2022, 2, 1, 10, 0, 5, 0, time.UTC),
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tunnels.Connections() = %v, want %v", got, want)
+	}
+}
+
+func TestTunnels_CleanupConnections(t *testing.T) {
+	tunnelID := uuid.MustParse("f70ff985-a4ef-4643-bbbc-4a0ed4fc8415")
+	tests := []struct {
+		name       string
+		statusCode int
+		wantErr    bool
+	}{
+		{
+			name:       "cleaned up",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "not found",
+			statusCode: http.StatusNotFound,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				wantPath := "/client/v4/accounts/account-id/tunnels/" + tunnelID.String() + "/connections"
+				if r.Method != http.MethodDelete || r.URL.Path != wantPath {
+					t.Errorf("request = %s %s, want DELETE %s", r.Method, r.URL.Path, wantPath)
+				}
+
+				w.WriteHeader(tt.statusCode)
+				writeTestResponse(w, struct{}{}, ResultInfo{})
+			}))
+			c.accountID = "account-id"
+
+			err := c.Tunnels().CleanupConnections(context.Background(), tunnelID)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("tunnels.CleanupConnections() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTunnels_UpdateConfiguration(t *testing.T) {
+	tunnelID := uuid.MustParse("f70ff985-a4ef-4643-bbbc-4a0ed4fc8415")
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wantPath := "/client/v4/accounts/account-id/cfd_tunnel/" + tunnelID.String() + "/configurations"
+		if r.Method != http.MethodPut || r.URL.Path != wantPath {
+			t.Errorf("request = %s %s, want PUT %s", r.Method, r.URL.Path, wantPath)
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		var got map[string]interface{}
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Fatal(err)
+		}
+
+		want := map[string]interface{}{
+			"config": map[string]interface{}{
+				"ingress": []interface{}{
+					map[string]interface{}{
+						"hostname":      "foo.example.com",
+						"service":       "http://foo.default:80",
+						"originRequest": map[string]interface{}{"httpHostHeader": "foo.example.com"},
+					},
+					map[string]interface{}{"service": "http_status:404"},
+				},
+				"originRequest": map[string]interface{}{"connectTimeout": float64(30)},
+				"warp-routing":  map[string]interface{}{"enabled": true},
+			},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("body = %v, want %v", got, want)
+		}
+
+		writeTestResponse(w, struct{}{}, ResultInfo{})
+	}))
+	c.accountID = "account-id"
+
+	err := c.Tunnels().UpdateConfiguration(context.Background(), tunnelID, &TunnelConfiguration{
+		Ingress: []TunnelIngressRule{
+			{
+				Hostname:      "foo.example.com",
+				Service:       "http://foo.default:80",
+				OriginRequest: &TunnelOriginRequest{HTTPHostHeader: "foo.example.com"},
+			},
+			{Service: "http_status:404"},
+		},
+		OriginRequest: &TunnelOriginRequest{ConnectTimeout: 30},
+		WarpRouting:   &TunnelWarpRouting{Enabled: true},
+	})
+	if err != nil {
+		t.Fatalf("tunnels.UpdateConfiguration() error = %v", err)
+	}
+}
+
+func TestParseTunnelToken(t *testing.T) {
+	tunnelID := uuid.MustParse("f70ff985-a4ef-4643-bbbc-4a0ed4fc8415")
+	tests := []struct {
+		name    string
+		token   string
+		want    *TunnelCredentials
+		wantErr bool
+	}{
+		{
+			name:  "valid",
+			token: base64.StdEncoding.EncodeToString([]byte(`{"a":"account-tag","s":"c2VjcmV0","t":"f70ff985-a4ef-4643-bbbc-4a0ed4fc8415"}`)),
+			want:  &TunnelCredentials{AccountTag: "account-tag", TunnelSecret: []byte("secret"), TunnelID: tunnelID},
+		},
+		{
+			name:    "not base64",
+			token:   "not a token",
+			wantErr: true,
+		},
+		{
+			name:    "not json",
+			token:   base64.StdEncoding.EncodeToString([]byte("account-tag")),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTunnelToken(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTunnelToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTunnelToken() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTunnels_GetByNameCache(t *testing.T) {
+	tunnelID := uuid.MustParse("f70ff985-a4ef-4643-bbbc-4a0ed4fc8415")
+	tests := []struct {
+		name         string
+		tunnels      []*Tunnel
+		deleteBefore bool
+		wantRequests int
+	}{
+		{
+			name:         "found tunnel is cached",
+			tunnels:      []*Tunnel{{ID: tunnelID, Name: "foo"}},
+			wantRequests: 1,
+		},
+		{
+			name:         "not found is not cached",
+			wantRequests: 2,
+		},
+		{
+			name:         "deleted tunnel is invalidated",
+			tunnels:      []*Tunnel{{ID: tunnelID, Name: "foo"}},
+			deleteBefore: true,
+			wantRequests: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodGet {
+					requests++
+				}
+
+				writeTestResponse(w, tt.tunnels, ResultInfo{})
+			}))
+			c.cache = newTTLCache(time.Minute)
+
+			_, _ = c.Tunnels().GetByName(context.Background(), "foo")
+			if tt.deleteBefore {
+				if err := c.Tunnels().Delete(context.Background(), tunnelID); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, _ = c.Tunnels().GetByName(context.Background(), "foo")
+			if requests != tt.wantRequests {
+				t.Errorf("tunnels.GetByName() requests = %v, want %v", requests, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestTunnels_GetCache(t *testing.T) {
+	tunnelID := uuid.MustParse("f70ff985-a4ef-4643-bbbc-4a0ed4fc8415")
+	tests := []struct {
+		name         string
+		tunnel       *Tunnel
+		deleteBefore bool
+		wantRequests int
+	}{
+		{
+			name:         "found tunnel is cached",
+			tunnel:       &Tunnel{ID: tunnelID, Name: "foo"},
+			wantRequests: 1,
+		},
+		{
+			name:         "not found is not cached",
+			wantRequests: 2,
+		},
+		{
+			name:         "deleted tunnel is invalidated",
+			tunnel:       &Tunnel{ID: tunnelID, Name: "foo"},
+			deleteBefore: true,
+			wantRequests: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet {
+					writeTestResponse(w, struct{}{}, ResultInfo{})
+					return
+				}
+
+				requests++
+				if tt.tunnel == nil {
+					w.WriteHeader(http.StatusNotFound)
+				}
+
+				writeTestResponse(w, tt.tunnel, ResultInfo{})
+			}))
+			c.cache = newTTLCache(time.Minute)
+
+			_, _ = c.Tunnels().Get(context.Background(), tunnelID)
+			if tt.deleteBefore {
+				if err := c.Tunnels().Delete(context.Background(), tunnelID); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, _ = c.Tunnels().Get(context.Background(), tunnelID)
+			if requests != tt.wantRequests {
+				t.Errorf("tunnels.Get() requests = %v, want %v", requests, tt.wantRequests)
+			}
+		})
+	}
+}
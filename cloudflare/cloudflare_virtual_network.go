@@ -0,0 +1,193 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+type VirtualNetworkClient interface {
+	List(ctx context.Context, opts *VirtualNetworkListOptions) ([]*VirtualNetwork, error)
+	GetByName(ctx context.Context, name string) (*VirtualNetwork, error)
+	Create(ctx context.Context, vnet *VirtualNetwork) (*VirtualNetwork, error)
+	Update(ctx context.Context, vnet *VirtualNetwork) error
+	Delete(ctx context.Context, vnetID string) error
+}
+
+// VirtualNetwork isolates the private network routes of the account
+// so the same network could be routed through several tunnels.
+type VirtualNetwork struct {
+	ID               string     `json:"id,omitempty"`
+	Name             string     `json:"name"`
+	Comment          string     `json:"comment"`
+	IsDefaultNetwork bool       `json:"is_default_network"`
+	CreatedAt        *time.Time `json:"created_at,omitempty"`
+	DeletedAt        *time.Time `json:"deleted_at,omitempty"`
+}
+
+type VirtualNetworkListOptions struct {
+	ID               string `url:"id,omitempty"`
+	Name             string `url:"name,omitempty"`
+	IsDefaultNetwork *bool  `url:"is_default_network,omitempty"`
+	// IsDeleted includes only the deleted virtual networks when true, and only the existing ones when false.
+	IsDeleted *bool `url:"is_deleted,omitempty"`
+}
+
+type virtualNetworks struct {
+	client *client
+}
+
+func newVirtualNetworks(c *client) *virtualNetworks {
+	return &virtualNetworks{
+		client: c,
+	}
+}
+
+// List retrieves the virtual networks of the account.
+//
+// API reference: https://api.cloudflare.com/#tunnel-virtual-network-list-virtual-networks
+func (s *virtualNetworks) List(ctx context.Context, opts *VirtualNetworkListOptions) ([]*VirtualNetwork, error) {
+	var vnetList []*VirtualNetwork
+	if opts == nil {
+		opts = &VirtualNetworkListOptions{}
+	}
+
+	s.client.logger.V(1).Info("Retriving virtual networks", "options", opts)
+	err := NewRequest(s.client).
+		Verb(http.MethodGet).
+		AccountPrefix(s.client.accountID).
+		Resource("teamnet/virtual_networks").
+		Param(opts).
+		DoPages(ctx, func(result RequestResult) error {
+			var page []*VirtualNetwork
+			if err := result.Into(&page); err != nil {
+				return err
+			}
+
+			vnetList = append(vnetList, page...)
+			return nil
+		})
+	return vnetList, err
+}
+
+// GetByName fetch an existing virtual network by name.
+func (s *virtualNetworks) GetByName(ctx context.Context, name string) (*VirtualNetwork, error) {
+	cacheKey := "vnet/" + name
+	if cached, ok := s.client.cache.Get(cacheKey); ok {
+		vnet := *cached.(*VirtualNetwork)
+		return &vnet, nil
+	}
+
+	s.client.logger.V(1).Info("Getting virtual network details by name", "name", name)
+	isDeleted := false
+	vnetList, err := s.List(ctx, &VirtualNetworkListOptions{
+		Name:      name,
+		IsDeleted: &isDeleted,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, vnet := range vnetList {
+		if vnet.Name == name {
+			cached := *vnet
+			s.client.cache.Set(cacheKey, &cached)
+			return vnet, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+// Create creates a new virtual network.
+//
+// API reference: https://api.cloudflare.com/#tunnel-virtual-network-create-virtual-network
+func (s *virtualNetworks) Create(ctx context.Context, vnet *VirtualNetwork) (*VirtualNetwork, error) {
+	s.client.logger.V(1).Info("Creating virtual network", "name", vnet.Name)
+	// The create endpoint names the default flag differently from the virtual network itself.
+	body := struct {
+		Name      string `json:"name"`
+		Comment   string `json:"comment"`
+		IsDefault bool   `json:"is_default"`
+	}{
+		Name:      vnet.Name,
+		Comment:   vnet.Comment,
+		IsDefault: vnet.IsDefaultNetwork,
+	}
+
+	created := &VirtualNetwork{}
+	err := NewRequest(s.client).
+		Verb(http.MethodPost).
+		AccountPrefix(s.client.accountID).
+		Resource("teamnet/virtual_networks").
+		Body(body).
+		Do(ctx).
+		Into(created)
+	return created, err
+}
+
+// Update overwrites the name, comment and default flag of a virtual network identified by vnet.ID.
+//
+// API reference: https://api.cloudflare.com/#tunnel-virtual-network-update-virtual-network
+func (s *virtualNetworks) Update(ctx context.Context, vnet *VirtualNetwork) error {
+	s.client.logger.V(1).Info("Updating virtual network", "vnet-id", vnet.ID)
+	s.client.cache.DeleteFunc(func(_ string, value interface{}) bool {
+		cached, ok := value.(*VirtualNetwork)
+		return ok && cached.ID == vnet.ID
+	})
+
+	body := struct {
+		Name             string `json:"name"`
+		Comment          string `json:"comment"`
+		IsDefaultNetwork bool   `json:"is_default_network"`
+	}{
+		Name:             vnet.Name,
+		Comment:          vnet.Comment,
+		IsDefaultNetwork: vnet.IsDefaultNetwork,
+	}
+
+	return NewRequest(s.client).
+		Verb(http.MethodPatch).
+		AccountPrefix(s.client.accountID).
+		Resource("teamnet/virtual_networks").
+		ResourceID(vnet.ID).
+		Body(body).
+		Do(ctx).
+		Error()
+}
+
+// Delete removes a virtual network. Cloudflare refuses to delete
+// the default virtual network or one which still has routes.
+//
+// API reference: https://api.cloudflare.com/#tunnel-virtual-network-delete-virtual-network
+func (s *virtualNetworks) Delete(ctx context.Context, vnetID string) error {
+	s.client.logger.V(1).Info("Deleting virtual network", "vnet-id", vnetID)
+	s.client.cache.DeleteFunc(func(_ string, value interface{}) bool {
+		cached, ok := value.(*VirtualNetwork)
+		return ok && cached.ID == vnetID
+	})
+
+	return NewRequest(s.client).
+		Verb(http.MethodDelete).
+		AccountPrefix(s.client.accountID).
+		Resource("teamnet/virtual_networks").
+		ResourceID(vnetID).
+		Do(ctx).
+		Error()
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestVirtualNetworks_GetByName(t *testing.T) {
+	tests := []struct {
+		name    string
+		vnets   []*VirtualNetwork
+		want    *VirtualNetwork
+		wantErr bool
+	}{
+		{
+			name:  "found",
+			vnets: []*VirtualNetwork{{ID: "vnet-id", Name: "cluster-a", IsDefaultNetwork: true}},
+			want:  &VirtualNetwork{ID: "vnet-id", Name: "cluster-a", IsDefaultNetwork: true},
+		},
+		{
+			name:    "not found",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet || r.URL.Path != "/client/v4/accounts/account-id/teamnet/virtual_networks" {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+				}
+
+				if got := r.URL.Query(); got.Get("name") != "cluster-a" || got.Get("is_deleted") != "false" {
+					t.Errorf("unexpected query %v", got)
+				}
+
+				writeTestResponse(w, tt.vnets, ResultInfo{})
+			}))
+			c.accountID = "account-id"
+
+			got, err := c.VirtualNetworks().GetByName(context.Background(), "cluster-a")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("virtualNetworks.GetByName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr && !IsNotFound(err) {
+				t.Errorf("virtualNetworks.GetByName() error = %v, want not found", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("virtualNetworks.GetByName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVirtualNetworks_Write(t *testing.T) {
+	tests := []struct {
+		name       string
+		do         func(ctx context.Context, c *client) error
+		wantMethod string
+		wantPath   string
+		wantBody   map[string]interface{}
+	}{
+		{
+			name: "create",
+			do: func(ctx context.Context, c *client) error {
+				_, err := c.VirtualNetworks().Create(ctx, &VirtualNetwork{Name: "cluster-a", Comment: "cluster a", IsDefaultNetwork: true})
+				return err
+			},
+			wantMethod: http.MethodPost,
+			wantPath:   "/client/v4/accounts/account-id/teamnet/virtual_networks",
+			wantBody:   map[string]interface{}{"name": "cluster-a", "comment": "cluster a", "is_default": true},
+		},
+		{
+			name: "update",
+			do: func(ctx context.Context, c *client) error {
+				return c.VirtualNetworks().Update(ctx, &VirtualNetwork{ID: "vnet-id", Name: "cluster-a", Comment: "cluster a"})
+			},
+			wantMethod: http.MethodPatch,
+			wantPath:   "/client/v4/accounts/account-id/teamnet/virtual_networks/vnet-id",
+			wantBody:   map[string]interface{}{"name": "cluster-a", "comment": "cluster a", "is_default_network": false},
+		},
+		{
+			name: "delete",
+			do: func(ctx context.Context, c *client) error {
+				return c.VirtualNetworks().Delete(ctx, "vnet-id")
+			},
+			wantMethod: http.MethodDelete,
+			wantPath:   "/client/v4/accounts/account-id/teamnet/virtual_networks/vnet-id",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gets int
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodGet {
+					gets++
+					writeTestResponse(w, []*VirtualNetwork{{ID: "vnet-id", Name: "cluster-a"}}, ResultInfo{})
+					return
+				}
+
+				if r.Method != tt.wantMethod || r.URL.Path != tt.wantPath {
+					t.Errorf("request = %s %s, want %s %s", r.Method, r.URL.Path, tt.wantMethod, tt.wantPath)
+				}
+
+				var body map[string]interface{}
+				if data, _ := ioutil.ReadAll(r.Body); len(data) != 0 {
+					if err := json.Unmarshal(data, &body); err != nil {
+						t.Errorf("unexpected body %s: %v", data, err)
+					}
+				}
+
+				if !reflect.DeepEqual(body, tt.wantBody) {
+					t.Errorf("body = %v, want %v", body, tt.wantBody)
+				}
+
+				writeTestResponse(w, VirtualNetwork{ID: "vnet-id", Name: "cluster-a"}, ResultInfo{})
+			}))
+			c.accountID = "account-id"
+			c.cache = newTTLCache(time.Minute)
+
+			ctx := context.Background()
+			if _, err := c.VirtualNetworks().GetByName(ctx, "cluster-a"); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := tt.do(ctx, c); err != nil {
+				t.Fatal(err)
+			}
+
+			// Writing the virtual network invalidates the cached lookup, except for creating a new one.
+			if _, err := c.VirtualNetworks().GetByName(ctx, "cluster-a"); err != nil {
+				t.Fatal(err)
+			}
+
+			wantGets := 2
+			if tt.wantMethod == http.MethodPost {
+				wantGets = 1
+			}
+
+			if gets != wantGets {
+				t.Errorf("virtualNetworks.GetByName() requests = %v, want %v", gets, wantGets)
+			}
+		})
+	}
+}
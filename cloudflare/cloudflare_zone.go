@@ -18,16 +18,26 @@ package cloudflare
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 )
 
 type ZoneClient interface {
 	Get(ctx context.Context, zoneID string) (*Zone, error)
+	GetByName(ctx context.Context, name string) (*Zone, error)
+	List(ctx context.Context, opts *ZoneListOptions) ([]*Zone, error)
 }
 
 type Zone struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status,omitempty"`
+}
+
+type ZoneListOptions struct {
+	Name      string `url:"name,omitempty"`
+	AccountID string `url:"account.id,omitempty"`
+	Status    string `url:"status,omitempty"`
 }
 
 type zones struct {
@@ -44,6 +54,12 @@ func newZones(c *client) *zones {
 //
 // API reference: https://api.cloudflare.com/#zone-zone-details
 func (s *zones) Get(ctx context.Context, zoneID string) (*Zone, error) {
+	cacheKey := "zone/" + zoneID
+	if cached, ok := s.client.cache.Get(cacheKey); ok {
+		zone := *cached.(*Zone)
+		return &zone, nil
+	}
+
 	s.client.logger.V(1).Info("Getting zone details", "zone-id", zoneID)
 	zone := &Zone{}
 	err := NewRequest(s.client).
@@ -52,5 +68,77 @@ func (s *zones) Get(ctx context.Context, zoneID string) (*Zone, error) {
 		ResourceID(zoneID).
 		Do(ctx).
 		Into(zone)
-	return zone, err
+	if err != nil {
+		return nil, err
+	}
+
+	cached := *zone
+	s.client.cache.Set(cacheKey, &cached)
+	return zone, nil
+}
+
+// GetByName fetch a zone by name. The zones are looked up in the client account when it is known.
+func (s *zones) GetByName(ctx context.Context, name string) (*Zone, error) {
+	s.client.logger.V(1).Info("Getting zone details by name", "name", name)
+	zoneList, err := s.List(ctx, &ZoneListOptions{
+		Name:      name,
+		AccountID: s.client.accountID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, zone := range zoneList {
+		if zone.Name == name {
+			return zone, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+// List retrieves the zones the credentials have access to.
+//
+// API reference: https://api.cloudflare.com/#zone-list-zones
+func (s *zones) List(ctx context.Context, opts *ZoneListOptions) ([]*Zone, error) {
+	var zoneList []*Zone
+	if opts == nil {
+		opts = &ZoneListOptions{}
+	}
+
+	cacheKey := fmt.Sprintf("zones/%s/%s/%s", opts.Name, opts.AccountID, opts.Status)
+	if cached, ok := s.client.cache.Get(cacheKey); ok {
+		return copyZones(cached.([]*Zone)), nil
+	}
+
+	s.client.logger.V(1).Info("Retriving zones", "options", opts)
+	err := NewRequest(s.client).
+		Verb(http.MethodGet).
+		Resource("zones").
+		Param(opts).
+		DoPages(ctx, func(result RequestResult) error {
+			var page []*Zone
+			if err := result.Into(&page); err != nil {
+				return err
+			}
+
+			zoneList = append(zoneList, page...)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	s.client.cache.Set(cacheKey, copyZones(zoneList))
+	return zoneList, nil
+}
+
+func copyZones(zoneList []*Zone) []*Zone {
+	result := make([]*Zone, 0, len(zoneList))
+	for _, zone := range zoneList {
+		zone := *zone
+		result = append(result, &zone)
+	}
+
+	return result
 }
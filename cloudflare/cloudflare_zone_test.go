@@ -0,0 +1,81 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestZones_GetByName(t *testing.T) {
+	zoneList := []*Zone{
+		{ID: "zone-a", Name: "example.com"},
+		{ID: "zone-b", Name: "example.org"},
+	}
+	tests := []struct {
+		name    string
+		zone    string
+		want    *Zone
+		wantErr bool
+	}{
+		{
+			name: "found",
+			zone: "example.org",
+			want: &Zone{ID: "zone-b", Name: "example.org"},
+		},
+		{
+			name:    "not found",
+			zone:    "example.net",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.URL.Query().Get("account.id"); got != "account-id" {
+					t.Errorf("account.id = %q, want %q", got, "account-id")
+				}
+
+				var result []*Zone
+				for _, zone := range zoneList {
+					if zone.Name == r.URL.Query().Get("name") {
+						result = append(result, zone)
+					}
+				}
+
+				writeTestResponse(w, result, ResultInfo{})
+			}))
+			c.accountID = "account-id"
+
+			got, err := c.Zones().GetByName(context.Background(), tt.zone)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("zones.GetByName() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if tt.wantErr && !IsNotFound(err) {
+				t.Errorf("zones.GetByName() error = %v, want not found", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("zones.GetByName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Cloudflare api error codes which are not distinguishable by the http status code.
+const (
+	ErrCodeTunnelAlreadyExists    = 1013
+	ErrCodeDNSRecordAlreadyExists = 81053
+	ErrCodeDNSRecordDuplicated    = 81057
+)
+
+var alreadyExistsCodes = []int{
+	ErrCodeTunnelAlreadyExists,
+	ErrCodeDNSRecordAlreadyExists,
+	ErrCodeDNSRecordDuplicated,
+}
+
+// APIError is an error returned by the cloudflare api.
+type APIError struct {
+	// StatusCode is the http status code of the response.
+	StatusCode int
+	// Errors is the list of errors in the response body.
+	Errors []Error
+	// RequestID is the CF-Ray of the response, useful when contacting cloudflare support.
+	RequestID string
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "cloudflare api error (status: %d", e.StatusCode)
+	if e.RequestID != "" {
+		fmt.Fprintf(&b, ", request id: %s", e.RequestID)
+	}
+
+	b.WriteString(")")
+	if len(e.Errors) == 0 {
+		fmt.Fprintf(&b, ": %s", http.StatusText(e.StatusCode))
+	}
+
+	for i, err := range e.Errors {
+		if i == 0 {
+			b.WriteString(": ")
+		} else {
+			b.WriteString("; ")
+		}
+
+		b.WriteString(err.Error())
+	}
+
+	return b.String()
+}
+
+// HasCode reports whether the error contains given cloudflare error code.
+func (e *APIError) HasCode(codes ...int) bool {
+	for _, err := range e.Errors {
+		code, convErr := err.Code.Int64()
+		if convErr != nil {
+			continue
+		}
+
+		for _, c := range codes {
+			if int(code) == c {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func asAPIError(err error) (*APIError, bool) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr, true
+	}
+
+	return nil, false
+}
+
+// IsNotFound reports whether the resource does not exist.
+func IsNotFound(err error) bool {
+	if errors.Is(err, ErrNotFound) {
+		return true
+	}
+
+	apiErr, ok := asAPIError(err)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+// IsConflict reports whether the request conflicts with the current state of the resource.
+func IsConflict(err error) bool {
+	apiErr, ok := asAPIError(err)
+	return ok && apiErr.StatusCode == http.StatusConflict
+}
+
+// IsAlreadyExists reports whether the resource being created already exists.
+func IsAlreadyExists(err error) bool {
+	apiErr, ok := asAPIError(err)
+	return ok && (apiErr.StatusCode == http.StatusConflict || apiErr.HasCode(alreadyExistsCodes...))
+}
+
+// IsRateLimited reports whether the request was rejected by the rate limiter.
+func IsRateLimited(err error) bool {
+	apiErr, ok := asAPIError(err)
+	return ok && apiErr.StatusCode == http.StatusTooManyRequests
+}
+
+// IsUnauthorized reports whether the credentials are invalid or lack the required permissions.
+func IsUnauthorized(err error) bool {
+	apiErr, ok := asAPIError(err)
+	return ok && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden)
+}
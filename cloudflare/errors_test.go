@@ -0,0 +1,106 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestRequestResult_Error(t *testing.T) {
+	tests := []struct {
+		name              string
+		statusCode        int
+		errors            []Error
+		wantNotFound      bool
+		wantAlreadyExists bool
+		wantRateLimited   bool
+		wantUnauthorized  bool
+		wantRequestID     string
+	}{
+		{
+			name:         "not found",
+			statusCode:   http.StatusNotFound,
+			errors:       []Error{{Code: "1003", Message: "tunnel not found"}},
+			wantNotFound: true,
+		},
+		{
+			name:              "already exists by error code",
+			statusCode:        http.StatusBadRequest,
+			errors:            []Error{{Code: json.Number(fmt.Sprint(ErrCodeTunnelAlreadyExists)), Message: "tunnel already exists"}},
+			wantAlreadyExists: true,
+		},
+		{
+			name:              "conflict",
+			statusCode:        http.StatusConflict,
+			wantAlreadyExists: true,
+		},
+		{
+			name:            "rate limited",
+			statusCode:      http.StatusTooManyRequests,
+			wantRateLimited: true,
+		},
+		{
+			name:             "forbidden",
+			statusCode:       http.StatusForbidden,
+			errors:           []Error{{Code: "10000", Message: "Authentication error"}},
+			wantUnauthorized: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("CF-Ray", "ray-id")
+				w.WriteHeader(tt.statusCode)
+				_ = json.NewEncoder(w).Encode(Response{Errors: tt.errors})
+			}))
+
+			err := NewRequest(c).
+				Verb(http.MethodGet).
+				Resource("items").
+				Do(context.Background()).
+				Error()
+			apiErr, ok := err.(*APIError)
+			if !ok {
+				t.Fatalf("RequestResult.Error() = %T, want *APIError", err)
+			}
+
+			if apiErr.StatusCode != tt.statusCode || apiErr.RequestID != "ray-id" {
+				t.Errorf("RequestResult.Error() = %v, want status %v and request id", apiErr, tt.statusCode)
+			}
+
+			if got := IsNotFound(err); got != tt.wantNotFound {
+				t.Errorf("IsNotFound() = %v, want %v", got, tt.wantNotFound)
+			}
+
+			if got := IsAlreadyExists(err); got != tt.wantAlreadyExists {
+				t.Errorf("IsAlreadyExists() = %v, want %v", got, tt.wantAlreadyExists)
+			}
+
+			if got := IsRateLimited(err); got != tt.wantRateLimited {
+				t.Errorf("IsRateLimited() = %v, want %v", got, tt.wantRateLimited)
+			}
+
+			if got := IsUnauthorized(err); got != tt.wantUnauthorized {
+				t.Errorf("IsUnauthorized() = %v, want %v", got, tt.wantUnauthorized)
+			}
+		})
+	}
+}
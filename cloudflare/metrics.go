@@ -0,0 +1,79 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	requestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cloudflare_api_requests_total",
+			Help: "Number of cloudflare api requests, partitioned by the response status code and cloudflare error code.",
+		},
+		[]string{"verb", "resource", "subpath", "code", "error_code"},
+	)
+
+	requestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "cloudflare_api_request_duration_seconds",
+			Help:    "Latency of cloudflare api requests, partitioned by the response status code.",
+			Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		},
+		[]string{"verb", "resource", "subpath", "code"},
+	)
+
+	rateLimiterWaitDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "cloudflare_api_rate_limiter_wait_duration_seconds",
+			Help:    "Time the cloudflare api requests are queued by the client-side rate limiter.",
+			Buckets: []float64{0.001, 0.01, 0.1, 0.5, 1, 2.5, 5, 10, 30, 60},
+		},
+		[]string{"account"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		requestsTotal,
+		requestDuration,
+		rateLimiterWaitDuration,
+	)
+}
+
+// observeRequest records a single http request attempt. The status code of a request
+// failed before getting any response is "error", and the error code is the first cloudflare
+// error code of the response, if any.
+func observeRequest(r *Request, statusCode int, errors []Error, duration time.Duration) {
+	code := "error"
+	if statusCode != 0 {
+		code = strconv.Itoa(statusCode)
+	}
+
+	var errorCode string
+	if len(errors) != 0 {
+		errorCode = errors[0].Code.String()
+	}
+
+	requestsTotal.WithLabelValues(r.verb, r.resource, r.subpath, code, errorCode).Inc()
+	requestDuration.WithLabelValues(r.verb, r.resource, r.subpath, code).Observe(duration.Seconds())
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRequest_Metrics(t *testing.T) {
+	tests := []struct {
+		name          string
+		statusCode    int
+		errors        []Error
+		wantCode      string
+		wantErrorCode string
+	}{
+		{
+			name:       "succeeded",
+			statusCode: http.StatusOK,
+			wantCode:   "200",
+		},
+		{
+			name:          "failed with error code",
+			statusCode:    http.StatusBadRequest,
+			errors:        []Error{{Code: "1013", Message: "tunnel already exists"}, {Code: "1000"}},
+			wantCode:      "400",
+			wantErrorCode: "1013",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.statusCode)
+				_ = json.NewEncoder(w).Encode(Response{Success: tt.errors == nil, Errors: tt.errors})
+			}))
+
+			counter := requestsTotal.WithLabelValues(http.MethodPost, "metrics", "test", tt.wantCode, tt.wantErrorCode)
+			before := testutil.ToFloat64(counter)
+			_ = NewRequest(c).
+				Verb(http.MethodPost).
+				Resource("metrics").
+				ResourceID("id").
+				SubPath("test").
+				Do(context.Background())
+			if got := testutil.ToFloat64(counter) - before; got != 1 {
+				t.Errorf("cloudflare_api_requests_total increased by %v, want 1", got)
+			}
+		})
+	}
+}
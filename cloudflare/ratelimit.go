@@ -0,0 +1,74 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimiter throttles the requests to the cloudflare api with a token bucket per account,
+// since cloudflare enforces the request budget account-wide (1200 requests per 5 minutes).
+// Share one RateLimiter across every client, so clients of the same account draw from the same bucket.
+type RateLimiter struct {
+	limit rate.Limit
+	burst int
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+// NewRateLimiter creates RateLimiter allowing limit requests per second with
+// bursts of at most burst requests for each account.
+func NewRateLimiter(limit rate.Limit, burst int) *RateLimiter {
+	return &RateLimiter{
+		limit:    limit,
+		burst:    burst,
+		limiters: make(map[string]*rate.Limiter),
+	}
+}
+
+// WithRateLimiter throttles the requests of the client account with the given RateLimiter.
+func WithRateLimiter(limiter *RateLimiter) ClientOption {
+	return func(c *client) error {
+		c.rateLimiter = limiter
+		return nil
+	}
+}
+
+// Wait blocks until a request of the account is allowed or ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context, accountID string) error {
+	start := time.Now()
+	err := l.accountLimiter(accountID).Wait(ctx)
+	rateLimiterWaitDuration.WithLabelValues(accountID).Observe(time.Since(start).Seconds())
+	return err
+}
+
+func (l *RateLimiter) accountLimiter(accountID string) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	limiter, ok := l.limiters[accountID]
+	if !ok {
+		limiter = rate.NewLimiter(l.limit, l.burst)
+		l.limiters[accountID] = limiter
+	}
+
+	return limiter
+}
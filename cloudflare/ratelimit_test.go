@@ -0,0 +1,68 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestRateLimiter_Wait(t *testing.T) {
+	interval := 100 * time.Millisecond
+	tests := []struct {
+		name     string
+		accounts []string
+		wantWait bool
+	}{
+		{
+			name:     "clients of the same account share the bucket",
+			accounts: []string{"account-a", "account-a"},
+			wantWait: true,
+		},
+		{
+			name:     "clients of different accounts",
+			accounts: []string{"account-a", "account-b"},
+			wantWait: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := NewRateLimiter(rate.Every(interval), 1)
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				writeTestResponse(w, nil, ResultInfo{})
+			})
+
+			start := time.Now()
+			for _, account := range tt.accounts {
+				c := newTestClient(t, handler)
+				c.accountID = account
+				c.rateLimiter = limiter
+				if err := NewRequest(c).Verb(http.MethodGet).Resource("items").Do(context.Background()).Error(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if waited := time.Since(start) >= interval/2; waited != tt.wantWait {
+				t.Errorf("RateLimiter.Wait() waited = %v, want %v", waited, tt.wantWait)
+			}
+		})
+	}
+}
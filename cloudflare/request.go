@@ -25,20 +25,23 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/go-logr/zapr"
 	"github.com/google/go-querystring/query"
-	"go.uber.org/multierr"
 	"go.uber.org/zap"
 )
 
 type Request struct {
-	baseURL *url.URL
-	client  *http.Client
-	logger  logr.Logger
+	baseURL     *url.URL
+	client      *http.Client
+	logger      logr.Logger
+	retryPolicy *RetryPolicy
+	rateLimiter *RateLimiter
+	accountID   string
 
 	verb       string
 	pathPrefix string
@@ -49,9 +52,15 @@ type Request struct {
 	headers http.Header
 	params  url.Values
 
+	// pagination
+	page    int
+	perPage int
+	cursor  string
+
 	// output
-	err  error
-	body io.Reader
+	err error
+	// body is kept as bytes so the request could be replayed on retry.
+	body []byte
 }
 
 func NewRequest(c *client) *Request {
@@ -68,9 +77,12 @@ func NewRequest(c *client) *Request {
 	}
 
 	return &Request{
-		client:  c.client,
-		baseURL: &base,
-		logger:  logger.WithName("http-request"),
+		client:      c.client,
+		baseURL:     &base,
+		logger:      logger.WithName("http-request"),
+		retryPolicy: c.retryPolicy,
+		rateLimiter: c.rateLimiter,
+		accountID:   c.accountID,
 	}
 }
 
@@ -126,18 +138,46 @@ func (r *Request) Param(params interface{}) *Request {
 	p, err := query.Values(params)
 	if err != nil {
 		r.err = err
+		return r
+	}
+
+	for k, v := range p {
+		r.params[k] = v
 	}
 
-	r.params = p
+	return r
+}
+
+// Page set the page number to fetch from paginated endpoint.
+func (r *Request) Page(page int) *Request {
+	r.page = page
+	return r
+}
+
+// PerPage set the number of items per page to fetch from paginated endpoint.
+func (r *Request) PerPage(perPage int) *Request {
+	r.perPage = perPage
+	return r
+}
+
+// Cursor set the cursor to fetch from cursor-based paginated endpoint.
+func (r *Request) Cursor(cursor string) *Request {
+	r.cursor = cursor
 	return r
 }
 
 func (r *Request) Body(body interface{}) *Request {
 	switch bt := body.(type) {
 	case []byte:
-		r.body = bytes.NewReader(bt)
-	case io.Reader:
 		r.body = bt
+	case io.Reader:
+		data, err := ioutil.ReadAll(bt)
+		if err != nil {
+			r.err = err
+			return r
+		}
+
+		r.body = data
 	default:
 		data, err := json.Marshal(bt)
 		if err != nil {
@@ -145,7 +185,7 @@ func (r *Request) Body(body interface{}) *Request {
 			return r
 		}
 
-		r.body = bytes.NewReader(data)
+		r.body = data
 	}
 
 	r.Header("Content-Type", "application/json")
@@ -154,15 +194,16 @@ func (r *Request) Body(body interface{}) *Request {
 
 // URL build url from given r.
 // url.Path will constructed as following:
+//
 //	/{basePath}/{pathPrefix}/{resourceName}/{resourceID}/{subPath}
 //
 // where are:
 //
-// 	- basePath		= `/client/v4`
-// 	- pathPrefix		= could be `/accounts/{account_id}` or `/zones/{zone_id}` (optional)
-// 	- resourceName		= cloudflare resources endpoint name eg: `/tunnels`
-// 	- resourceID		= cloudflare resource id `/:uuid` (optional)
-// 	- subPath		= cloudflare sub resource endpoint eg: `/routes`
+//   - basePath		= `/client/v4`
+//   - pathPrefix		= could be `/accounts/{account_id}` or `/zones/{zone_id}` (optional)
+//   - resourceName		= cloudflare resources endpoint name eg: `/tunnels`
+//   - resourceID		= cloudflare resource id `/:uuid` (optional)
+//   - subPath		= cloudflare sub resource endpoint eg: `/routes`
 //
 // beside path, this function also construct url.Query from given r.params
 func (r *Request) URL() *url.URL {
@@ -182,10 +223,28 @@ func (r *Request) URL() *url.URL {
 		p = path.Join(p, r.subpath)
 	}
 
-	url := baseURL
+	params := url.Values{}
+	for k, v := range r.params {
+		params[k] = v
+	}
+
+	if r.page > 0 {
+		params.Set("page", strconv.Itoa(r.page))
+	}
+
+	if r.perPage > 0 {
+		params.Set("per_page", strconv.Itoa(r.perPage))
+	}
+
+	if r.cursor != "" {
+		params.Set("cursor", r.cursor)
+	}
+
+	// Copy the base url, the request is sent again when paging or retrying.
+	url := *baseURL
 	url.Path = p
-	url.RawQuery = r.params.Encode()
-	return url
+	url.RawQuery = params.Encode()
+	return &url
 }
 
 func (r *Request) Do(ctx context.Context) RequestResult {
@@ -194,11 +253,47 @@ func (r *Request) Do(ctx context.Context) RequestResult {
 		return RequestResult{err: r.err}
 	}
 
+	for attempt := 0; ; attempt++ {
+		result, resp := r.do(ctx)
+		if r.retryPolicy == nil || attempt >= r.retryPolicy.MaxRetries || ctx.Err() != nil {
+			return result
+		}
+
+		if !r.retryPolicy.shouldRetry(r.verb, result.statusCode, result.transportErr) {
+			return result
+		}
+
+		backoff := r.retryPolicy.backoff(attempt, resp)
+		r.logger.V(1).Info("Retrying http request", "method", r.verb, "url", r.URL().String(), "status", result.statusCode, "attempt", attempt+1, "after", backoff)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result
+		case <-timer.C:
+		}
+	}
+}
+
+// do makes a single http request attempt.
+func (r *Request) do(ctx context.Context) (RequestResult, *http.Response) {
 	var result RequestResult
-	req, err := http.NewRequest(r.verb, r.URL().String(), r.body)
+	var body io.Reader
+	if r.body != nil {
+		body = bytes.NewReader(r.body)
+	}
+
+	req, err := http.NewRequest(r.verb, r.URL().String(), body)
 	if err != nil {
 		r.logger.V(1).Error(err, "Unable to make http request")
-		return RequestResult{err: err}
+		return RequestResult{err: err}, nil
+	}
+
+	if r.rateLimiter != nil {
+		if err := r.rateLimiter.Wait(ctx, r.accountID); err != nil {
+			r.logger.V(1).Error(err, "Unable to wait for rate limiter")
+			return RequestResult{err: err}, nil
+		}
 	}
 
 	req = req.WithContext(ctx)
@@ -207,28 +302,76 @@ func (r *Request) Do(ctx context.Context) RequestResult {
 	resp, err := r.client.Do(req)
 	if err != nil {
 		r.logger.V(1).Error(err, "Failed calling http request")
-		return RequestResult{err: err}
+		observeRequest(r, 0, nil, time.Since(reqTime))
+		return RequestResult{err: err, transportErr: err}, nil
 	}
 
 	defer resp.Body.Close()
+	var res Response
+	defer func() {
+		observeRequest(r, resp.StatusCode, res.Errors, time.Since(reqTime))
+	}()
+
 	r.logger.V(1).Info("Calling http request", "method", req.Method, "url", req.URL.String(), "status", resp.Status, "in", time.Since(reqTime))
+	requestID := resp.Header.Get("CF-Ray")
 	data, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		r.logger.V(1).Error(err, "Unable to read response body")
-		return RequestResult{err: err, statusCode: resp.StatusCode}
+		return RequestResult{err: err, statusCode: resp.StatusCode, requestID: requestID}, resp
 	}
 
-	var res Response
 	if err := json.NewDecoder(bytes.NewReader(data)).Decode(&res); err != nil {
 		r.logger.V(1).Error(err, "Unable to decode response body")
-		return RequestResult{err: err, statusCode: resp.StatusCode}
+		return RequestResult{err: err, statusCode: resp.StatusCode, requestID: requestID}, resp
 	}
 
 	result.raw = data
 	result.err = err
 	result.res = res
 	result.statusCode = resp.StatusCode
-	return result
+	result.requestID = requestID
+	return result, resp
+}
+
+// DoPages calls Do for every page of a paginated endpoint and call fn with the result of each page.
+// Pages are followed by the result_info cursor when the endpoint returns one, otherwise by the
+// page number until the last page. fn may return ErrStopPaging to stop the iteration early.
+func (r *Request) DoPages(ctx context.Context, fn func(RequestResult) error) error {
+	if r.page == 0 && r.cursor == "" {
+		r.page = 1
+	}
+
+	for {
+		result := r.Do(ctx)
+		if err := result.Error(); err != nil {
+			return err
+		}
+
+		if err := fn(result); err != nil {
+			if err == ErrStopPaging {
+				return nil
+			}
+
+			return err
+		}
+
+		info := result.ResultInfo()
+		if cursor := info.NextCursor(); cursor != "" {
+			if cursor == r.cursor || info.Count == 0 {
+				return nil
+			}
+
+			r.page = 0
+			r.cursor = cursor
+			continue
+		}
+
+		if !info.HasNextPage() {
+			return nil
+		}
+
+		r.page = info.Page + 1
+	}
 }
 
 type RequestResult struct {
@@ -236,20 +379,24 @@ type RequestResult struct {
 	res        Response
 	err        error
 	statusCode int
+
+	requestID string
+
+	// transportErr is set when the request failed without any response.
+	transportErr error
 }
 
 func (r RequestResult) Error() error {
-	if r.err != nil {
+	if r.err != nil && r.statusCode < http.StatusBadRequest {
 		return r.err
 	}
 
-	if len(r.res.Errors) != 0 {
-		var err error
-		for _, e := range r.res.Errors {
-			err = multierr.Append(err, e)
+	if r.statusCode >= http.StatusBadRequest || len(r.res.Errors) != 0 {
+		return &APIError{
+			StatusCode: r.statusCode,
+			Errors:     r.res.Errors,
+			RequestID:  r.requestID,
 		}
-
-		return err
 	}
 
 	return nil
@@ -263,6 +410,10 @@ func (r RequestResult) StatusCode() int {
 	return r.statusCode
 }
 
+func (r RequestResult) ResultInfo() ResultInfo {
+	return r.res.ResultInfo
+}
+
 func (r RequestResult) Into(o interface{}) error {
 	if err := r.Error(); err != nil {
 		return err
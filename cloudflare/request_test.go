@@ -0,0 +1,263 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, handler http.Handler) *client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	c, err := NewClient(
+		WithBaseURL(srv.URL+"/client/v4"),
+		WithHTTPClient(srv.Client()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c.(*client)
+}
+
+func writeTestResponse(w http.ResponseWriter, result interface{}, info ResultInfo) {
+	raw, _ := json.Marshal(result)
+	_ = json.NewEncoder(w).Encode(Response{
+		Success:    true,
+		Result:     raw,
+		ResultInfo: info,
+	})
+}
+
+func TestRequest_DoPages(t *testing.T) {
+	pages := [][]string{{"a", "b"}, {"c", "d"}, {"e"}}
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		stopAt  string
+		want    []string
+	}{
+		{
+			name: "page number",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+				writeTestResponse(w, pages[page-1], ResultInfo{
+					Page:       page,
+					TotalPages: len(pages),
+					Count:      len(pages[page-1]),
+				})
+			},
+			want: []string{"a", "b", "c", "d", "e"},
+		},
+		{
+			name: "page number without total pages",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+				writeTestResponse(w, pages[page-1], ResultInfo{
+					Page:    page,
+					PerPage: 2,
+					Count:   len(pages[page-1]),
+					Total:   5,
+				})
+			},
+			want: []string{"a", "b", "c", "d", "e"},
+		},
+		{
+			name: "page number without totals",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+				writeTestResponse(w, pages[page-1], ResultInfo{
+					Page:    page,
+					PerPage: 2,
+					Count:   len(pages[page-1]),
+				})
+			},
+			want: []string{"a", "b", "c", "d", "e"},
+		},
+		{
+			name: "cursor",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				page := 0
+				if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+					page, _ = strconv.Atoi(cursor)
+				}
+
+				info := ResultInfo{Count: len(pages[page])}
+				if page+1 < len(pages) {
+					info.Cursors.After = strconv.Itoa(page + 1)
+				}
+
+				writeTestResponse(w, pages[page], info)
+			},
+			want: []string{"a", "b", "c", "d", "e"},
+		},
+		{
+			name: "stop paging",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+				writeTestResponse(w, pages[page-1], ResultInfo{
+					Page:       page,
+					TotalPages: len(pages),
+					Count:      len(pages[page-1]),
+				})
+			},
+			stopAt: "b",
+			want:   []string{"a", "b"},
+		},
+		{
+			name: "not paginated",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				writeTestResponse(w, pages[0], ResultInfo{})
+			},
+			want: []string{"a", "b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/client/v4/items" {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+				}
+
+				tt.handler(w, r)
+			}))
+			var got []string
+			err := NewRequest(c).
+				Verb(http.MethodGet).
+				Resource("items").
+				DoPages(context.Background(), func(result RequestResult) error {
+					var page []string
+					if err := result.Into(&page); err != nil {
+						return err
+					}
+
+					for _, item := range page {
+						got = append(got, item)
+						if item == tt.stopAt {
+							return ErrStopPaging
+						}
+					}
+
+					return nil
+				})
+			if err != nil {
+				t.Errorf("Request.DoPages() error = %v", err)
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Request.DoPages() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequest_DoRetry(t *testing.T) {
+	policy := RetryPolicy{
+		MaxRetries: 2,
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 10 * time.Millisecond,
+	}
+	tests := []struct {
+		name         string
+		verb         string
+		statuses     []int
+		wantStatus   int
+		wantAttempts int
+	}{
+		{
+			name:         "rate limited then succeeded",
+			verb:         http.MethodPost,
+			statuses:     []int{http.StatusTooManyRequests, http.StatusOK},
+			wantStatus:   http.StatusOK,
+			wantAttempts: 2,
+		},
+		{
+			name:         "server error on idempotent verb",
+			verb:         http.MethodGet,
+			statuses:     []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK},
+			wantStatus:   http.StatusOK,
+			wantAttempts: 3,
+		},
+		{
+			name:         "server error on non-idempotent verb",
+			verb:         http.MethodPost,
+			statuses:     []int{http.StatusInternalServerError, http.StatusOK},
+			wantStatus:   http.StatusInternalServerError,
+			wantAttempts: 1,
+		},
+		{
+			name:         "retries exhausted",
+			verb:         http.MethodDelete,
+			statuses:     []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusOK},
+			wantStatus:   http.StatusBadGateway,
+			wantAttempts: 3,
+		},
+		{
+			name:         "client error",
+			verb:         http.MethodGet,
+			statuses:     []int{http.StatusNotFound, http.StatusOK},
+			wantStatus:   http.StatusNotFound,
+			wantAttempts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int
+			var bodies []string
+			c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := ioutil.ReadAll(r.Body)
+				bodies = append(bodies, string(b))
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(tt.statuses[attempts])
+				attempts++
+				writeTestResponse(w, nil, ResultInfo{})
+			}))
+			c.retryPolicy = &policy
+
+			got := NewRequest(c).
+				Verb(tt.verb).
+				Resource("items").
+				Body(strings.NewReader(`{"name":"foo"}`)).
+				Do(context.Background())
+			if got.StatusCode() != tt.wantStatus {
+				t.Errorf("Request.Do() status = %v, want %v", got.StatusCode(), tt.wantStatus)
+			}
+
+			if attempts != tt.wantAttempts {
+				t.Errorf("Request.Do() attempts = %v, want %v", attempts, tt.wantAttempts)
+			}
+
+			for _, body := range bodies {
+				if body != `{"name":"foo"}` {
+					t.Errorf("Request.Do() body = %q, want replayed body", body)
+				}
+			}
+		})
+	}
+}
@@ -45,6 +45,34 @@ type ResultInfo struct {
 	Cursors    ResultInfoCursors `json:"cursors"`
 }
 
+// NextCursor returns the cursor of the next page, or empty string
+// when the endpoint does not use cursor-based pagination.
+func (i ResultInfo) NextCursor() string {
+	if i.Cursors.After != "" {
+		return i.Cursors.After
+	}
+
+	return i.Cursor
+}
+
+// HasNextPage reports whether a page-number paginated endpoint has pages after this one. Some
+// endpoints leave out total_pages, so the last page is then worked out from total_count and
+// per_page, or assumed when the page is not full.
+func (i ResultInfo) HasNextPage() bool {
+	switch {
+	case i.Page == 0:
+		return false
+	case i.TotalPages > 0:
+		return i.Page < i.TotalPages
+	case i.PerPage == 0:
+		return false
+	case i.Total > 0:
+		return i.Page*i.PerPage < i.Total
+	default:
+		return i.Count == i.PerPage
+	}
+}
+
 type Response struct {
 	Success    bool            `json:"success,omitempty"`
 	Errors     []Error         `json:"errors,omitempty"`
@@ -0,0 +1,135 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultRetryPolicy is a reasonable retry policy for the cloudflare api.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 4,
+	MinBackoff: 500 * time.Millisecond,
+	MaxBackoff: 30 * time.Second,
+}
+
+// RetryPolicy defines how a failed request is retried.
+type RetryPolicy struct {
+	// MaxRetries is the maximum number of retries after the first attempt.
+	MaxRetries int
+	// MinBackoff is the backoff before the first retry, it doubles on every next retry.
+	MinBackoff time.Duration
+	// MaxBackoff is the upper bound of the backoff, including the Retry-After response header.
+	MaxBackoff time.Duration
+	// RetryNonIdempotent allows retrying transport errors and 5xx responses of
+	// non-idempotent requests (POST and PATCH). Rate limited requests are always
+	// retried since they are rejected before being processed.
+	RetryNonIdempotent bool
+}
+
+// WithRetryPolicy retries failed requests with the given policy.
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(c *client) error {
+		c.retryPolicy = &policy
+		return nil
+	}
+}
+
+// shouldRetry reports whether a request with given verb that ends up
+// with given response status code or transport error should be retried.
+func (p *RetryPolicy) shouldRetry(verb string, statusCode int, err error) bool {
+	if statusCode == http.StatusTooManyRequests {
+		return true
+	}
+
+	if err == nil && statusCode < http.StatusInternalServerError {
+		return false
+	}
+
+	return p.RetryNonIdempotent || isIdempotent(verb)
+}
+
+// backoff returns how long to wait before the given retry attempt (starting from 0).
+// The backoff is jittered exponential, unless the server asks to wait through the Retry-After header.
+func (p *RetryPolicy) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			if p.MaxBackoff > 0 && d > p.MaxBackoff {
+				return p.MaxBackoff
+			}
+
+			return d
+		}
+	}
+
+	backoff := p.MinBackoff
+	for i := 0; i < attempt; i++ {
+		backoff *= 2
+		if p.MaxBackoff > 0 && backoff >= p.MaxBackoff {
+			backoff = p.MaxBackoff
+			break
+		}
+	}
+
+	if backoff <= 0 {
+		return 0
+	}
+
+	// Full jitter within the upper half of the backoff, so the retries of
+	// concurrent reconcilers does not hit the api at the same time.
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+func isIdempotent(verb string) bool {
+	switch verb {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// parseRetryAfter parses Retry-After header value which could be either
+// delay in seconds or an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+
+		return d, true
+	}
+
+	return 0, false
+}
@@ -44,3 +44,24 @@ func (t *ServiceKeyTransport) RoundTrip(r *http.Request) (*http.Response, error)
 	bodyIsClosed = true
 	return t.Base.RoundTrip(rr)
 }
+
+// UserAgentTransport set the User-Agent header of every request.
+type UserAgentTransport struct {
+	Base      http.RoundTripper
+	UserAgent string
+}
+
+func (t *UserAgentTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	rr := r.Clone(r.Context())
+	if rr.Header == nil {
+		rr.Header = make(http.Header)
+	}
+
+	rr.Header.Set("User-Agent", t.UserAgent)
+	return base.RoundTrip(rr)
+}
@@ -0,0 +1,45 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+// Event reasons recorded by the reconcilers.
+const (
+	ReasonCloudTunnelCreated      = "CloudTunnelCreated"
+	ReasonCloudTunnelAdopted      = "CloudTunnelAdopted"
+	ReasonSecretCreated           = "SecretCreated"
+	ReasonRouteAdded              = "RouteAdded"
+	ReasonRouteRemoved            = "RouteRemoved"
+	ReasonRouteFailed             = "RouteFailed"
+	ReasonConfigRolledOut         = "ConfigRolledOut"
+	ReasonInvalidConfig           = "InvalidConfig"
+	ReasonReconcileFailed         = "ReconcileFailed"
+	ReasonDeletionBlocked         = "DeletionBlocked"
+	ReasonTunnelCreated           = "TunnelCreated"
+	ReasonTunnelFailed            = "TunnelFailed"
+	ReasonScaledDown              = "ScaledDown"
+	ReasonWaitingForConnectors    = "WaitingForConnectors"
+	ReasonConnectionsCleanedUp    = "ConnectionsCleanedUp"
+	ReasonCloudTunnelDeleted      = "CloudTunnelDeleted"
+	ReasonNetworkRouteCreated     = "NetworkRouteCreated"
+	ReasonNetworkRouteDeleted     = "NetworkRouteDeleted"
+	ReasonInvalidNetworkRoute     = "InvalidNetworkRoute"
+	ReasonVirtualNetworkCreated   = "VirtualNetworkCreated"
+	ReasonVirtualNetworkDeleted   = "VirtualNetworkDeleted"
+	ReasonVirtualNetworkInUse     = "VirtualNetworkInUse"
+	ReasonVirtualNetworkLeft      = "VirtualNetworkLeft"
+	ReasonInvalidConfigurationRef = "InvalidConfigurationRef"
+)
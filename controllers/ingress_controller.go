@@ -92,25 +92,31 @@ func (r *IngressReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_
 	tunnel := ir.Tunnel()
 	tunnelConfig, err := util.TunnelConfigurationFromIngress(ctx, r.Client, ing)
 	if err != nil {
+		r.Recorder.Eventf(ing, corev1.EventTypeWarning, ReasonReconcileFailed, "Unable to get tunnel configuration: %v", err)
 		return ctrl.Result{}, err
 	}
 
-	if _, err := controllerutil.CreateOrPatch(ctx, r.Client, tunnel, func() error {
+	op, err := controllerutil.CreateOrPatch(ctx, r.Client, tunnel, func() error {
 		tunnel.Spec.TunnelConfigurationSpec = tunnelConfig.Spec
 		tunnel.Spec.IngressRules = ir.TunnelIngressRules()
+		tunnel.Spec.LoadBalancer = ir.TunnelLoadBalancer()
 		return controllerutil.SetControllerReference(ing, tunnel, r.Scheme)
-	}); err != nil {
+	})
+	if err != nil {
+		r.Recorder.Eventf(ing, corev1.EventTypeWarning, ReasonReconcileFailed, "Unable to reconcile tunnel %s: %v", tunnel.Name, err)
 		return ctrl.Result{}, err
 	}
 
-	if tunnel.Status.Zone == "" {
+	if op == controllerutil.OperationResultCreated {
+		r.Recorder.Eventf(ing, corev1.EventTypeNormal, ReasonTunnelCreated, "Created tunnel %s", tunnel.Name)
+	}
+
+	if tunnel.Status.Target == "" {
 		return ctrl.Result{Requeue: true}, nil
 	}
 
 	ing.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{
-		{
-			Hostname: tunnel.Status.Zone,
-		},
+		{Hostname: tunnel.Status.Target},
 	}
 
 	return ctrl.Result{}, nil
@@ -0,0 +1,143 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	cloudflaredv1alpha1 "github.com/prksu/cloudflared-controller/api/v1alpha1"
+)
+
+// Route types and operations of RouteFailuresTotal.
+const (
+	RouteTypeDNS          = "dns"
+	RouteTypeLoadBalancer = "load_balancer"
+	RouteTypeNetwork      = "network"
+
+	RouteOperationCreate = "create"
+	RouteOperationDelete = "delete"
+)
+
+// tunnelMetricsTimeout bounds the listing of Tunnels on every scrape.
+const tunnelMetricsTimeout = 5 * time.Second
+
+var (
+	// RouteFailuresTotal counts the failed attempts to create or delete a route of Tunnel.
+	RouteFailuresTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cloudflared_controller_route_failures_total",
+			Help: "Number of failed attempts to create or delete a tunnel route, partitioned by route type and operation.",
+		},
+		[]string{"type", "operation"},
+	)
+
+	// ConfigRolloutsTotal counts the cloudflared restarts triggered by a change of Tunnel ConfigMap.
+	ConfigRolloutsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cloudflared_controller_config_rollouts_total",
+			Help: "Number of cloudflared rollouts triggered by a change of the tunnel configuration ConfigMap.",
+		},
+		[]string{"namespace", "tunnel"},
+	)
+
+	// IngressRoutedDuration observes the time from the Ingress creation until its hostnames are first routed.
+	IngressRoutedDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "cloudflared_controller_ingress_routed_duration_seconds",
+			Help:    "Time from the Ingress creation until its hostnames are routed to the tunnel.",
+			Buckets: []float64{1, 5, 10, 30, 60, 120, 300, 600, 1800},
+		},
+	)
+
+	tunnelsDesc = prometheus.NewDesc(
+		"cloudflared_controller_tunnels",
+		"Number of Tunnels, partitioned by readiness.",
+		[]string{"ready"}, nil,
+	)
+
+	tunnelRoutesDesc = prometheus.NewDesc(
+		"cloudflared_controller_tunnel_routes",
+		"Number of hostnames routed to the Tunnel, partitioned by zone.",
+		[]string{"namespace", "tunnel", "zone"}, nil,
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		RouteFailuresTotal,
+		ConfigRolloutsTotal,
+		IngressRoutedDuration,
+	)
+}
+
+// TunnelCollector collects the Tunnel gauges from the Tunnels at scrape time, so the
+// series of deleted Tunnels and removed routes go away along with them.
+type TunnelCollector struct {
+	Reader client.Reader
+}
+
+// Describe implements prometheus.Collector.
+func (c *TunnelCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- tunnelsDesc
+	ch <- tunnelRoutesDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *TunnelCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), tunnelMetricsTimeout)
+	defer cancel()
+
+	tunnelList := &cloudflaredv1alpha1.TunnelList{}
+	if err := c.Reader.List(ctx, tunnelList); err != nil {
+		ctrl.Log.WithName("metrics").Error(err, "unable to list Tunnels")
+		return
+	}
+
+	var ready, notReady int
+	for i := range tunnelList.Items {
+		tunnel := &tunnelList.Items[i]
+		if tunnelReady(tunnel) {
+			ready++
+		} else {
+			notReady++
+		}
+
+		routes := make(map[string]int)
+		for _, zone := range tunnel.Status.Zones {
+			routes[zone.Zone]++
+		}
+
+		for zone, count := range routes {
+			ch <- prometheus.MustNewConstMetric(tunnelRoutesDesc, prometheus.GaugeValue, float64(count), tunnel.Namespace, tunnel.Name, zone)
+		}
+	}
+
+	ch <- prometheus.MustNewConstMetric(tunnelsDesc, prometheus.GaugeValue, float64(ready), "true")
+	ch <- prometheus.MustNewConstMetric(tunnelsDesc, prometheus.GaugeValue, float64(notReady), "false")
+}
+
+// tunnelReady reports whether the Ready condition of Tunnel is true.
+func tunnelReady(tunnel *cloudflaredv1alpha1.Tunnel) bool {
+	return meta.IsStatusConditionTrue(tunnel.Status.Conditions, cloudflaredv1alpha1.ReadyCondition)
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	cloudflaredv1alpha1 "github.com/prksu/cloudflared-controller/api/v1alpha1"
+)
+
+func TestTunnelCollector(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(cloudflaredv1alpha1.AddToScheme(scheme))
+
+	tests := []struct {
+		name    string
+		tunnels []client.Object
+		want    string
+	}{
+		{
+			name: "no tunnels",
+			want: `
+# HELP cloudflared_controller_tunnels Number of Tunnels, partitioned by readiness.
+# TYPE cloudflared_controller_tunnels gauge
+cloudflared_controller_tunnels{ready="false"} 0
+cloudflared_controller_tunnels{ready="true"} 0
+`,
+		},
+		{
+			name: "tunnels with routes",
+			tunnels: []client.Object{
+				&cloudflaredv1alpha1.Tunnel{
+					ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+					Status: cloudflaredv1alpha1.TunnelStatus{
+						Conditions: []metav1.Condition{
+							{Type: cloudflaredv1alpha1.ReadyCondition, Status: metav1.ConditionTrue},
+						},
+						Zones: []cloudflaredv1alpha1.TunnelRouteZone{
+							{Hostname: "a.example.com", Zone: "example.com", ZoneID: "zone-a"},
+							{Hostname: "b.example.com", Zone: "example.com", ZoneID: "zone-a"},
+							{Hostname: "a.example.org", Zone: "example.org", ZoneID: "zone-b"},
+						},
+					},
+				},
+				&cloudflaredv1alpha1.Tunnel{
+					ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "default"},
+				},
+			},
+			want: `
+# HELP cloudflared_controller_tunnel_routes Number of hostnames routed to the Tunnel, partitioned by zone.
+# TYPE cloudflared_controller_tunnel_routes gauge
+cloudflared_controller_tunnel_routes{namespace="default",tunnel="foo",zone="example.com"} 2
+cloudflared_controller_tunnel_routes{namespace="default",tunnel="foo",zone="example.org"} 1
+# HELP cloudflared_controller_tunnels Number of Tunnels, partitioned by readiness.
+# TYPE cloudflared_controller_tunnels gauge
+cloudflared_controller_tunnels{ready="false"} 1
+cloudflared_controller_tunnels{ready="true"} 1
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := &TunnelCollector{
+				Reader: fake.NewClientBuilder().WithScheme(scheme).WithObjects(tt.tunnels...).Build(),
+			}
+			if err := testutil.CollectAndCompare(collector, strings.NewReader(tt.want)); err != nil {
+				t.Errorf("TunnelCollector.Collect() %v", err)
+			}
+		})
+	}
+}
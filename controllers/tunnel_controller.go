@@ -19,18 +19,27 @@ package controllers
 import (
 	"context"
 	"encoding/json"
-	"os"
+	"fmt"
 	"time"
 
+	"github.com/google/uuid"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	cloudflaredv1alpha1 "github.com/prksu/cloudflared-controller/api/v1alpha1"
 	"github.com/prksu/cloudflared-controller/cloudflare"
@@ -42,6 +51,9 @@ import (
 
 const (
 	TunnelControllerName = "cloudflared.cloudflare.com/tunnel-controller"
+
+	// connectorTerminationPollPeriod is how often the connector pods are checked while waiting them to terminate.
+	connectorTerminationPollPeriod = 5 * time.Second
 )
 
 // TunnelReconciler reconciles a Tunnel object
@@ -49,22 +61,49 @@ type TunnelReconciler struct {
 	client.Client
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
+	// ClientCache shares the cloudflare clients between reconciles.
+	ClientCache *util.ClientCache
+	// ResyncPeriod is how often the Tunnel is reconciled to observe its connectors. Zero disables the resync.
+	ResyncPeriod time.Duration
 }
 
-// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;patch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;patch;delete
 // +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;patch
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;patch
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 // +kubebuilder:rbac:groups=cloudflared.cloudflare.com,resources=tunnels,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=cloudflared.cloudflare.com,resources=tunnels/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=cloudflared.cloudflare.com,resources=tunnels/finalizers,verbs=update
+// +kubebuilder:rbac:groups=cloudflared.cloudflare.com,resources=tunnelnetworkroutes,verbs=get;list;watch
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *TunnelReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager) error {
+	if err := metrics.Registry.Register(&TunnelCollector{Reader: mgr.GetClient()}); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&cloudflaredv1alpha1.Tunnel{}).
+		Watches(
+			&source.Kind{Type: &cloudflaredv1alpha1.TunnelNetworkRoute{}},
+			handler.EnqueueRequestsFromMapFunc(tunnelNetworkRouteToTunnel),
+		).
 		Complete(r)
 }
 
+// tunnelNetworkRouteToTunnel maps the TunnelNetworkRoute to the Tunnel it references.
+func tunnelNetworkRouteToTunnel(o client.Object) []ctrl.Request {
+	route, ok := o.(*cloudflaredv1alpha1.TunnelNetworkRoute)
+	if !ok {
+		return nil
+	}
+
+	return []ctrl.Request{
+		{NamespacedName: client.ObjectKey{Namespace: route.Namespace, Name: route.Spec.TunnelRef.Name}},
+	}
+}
+
 func (r *TunnelReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
 	log := log.FromContext(ctx)
 	tunnel := &cloudflaredv1alpha1.Tunnel{}
@@ -78,187 +117,960 @@ func (r *TunnelReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ c
 		return ctrl.Result{}, err
 	}
 
-	ocsecret, err := util.GetOriginCertSecret(ctx, r.Client, req.Namespace, tunnel.Spec.OriginCert)
-	if err != nil {
-		return ctrl.Result{}, err
-	}
-
-	cfclient, err := cloudflare.NewClient(
-		cloudflare.WithAPIToken(os.Getenv(cloudflare.APITokenEnv)),
-		cloudflare.WithOriginCert(ocsecret.Data["cert.pem"]),
-		cloudflare.WithLogger(log),
-	)
-	if err != nil {
-		return ctrl.Result{}, err
-	}
-
 	patcher, err := patch.NewPatcher(tunnel, r.Client)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
 	defer func() {
+		setTunnelReadyCondition(tunnel)
 		if err := patcher.Patch(ctx, tunnel); err != nil {
 			log.Error(err, "unable patch Tunnel resource")
 			reterr = err
 		}
 	}()
 
+	cfclient, err := r.ClientCache.Get(ctx, r.Client, req.Namespace, tunnel.Spec.TunnelConfigurationSpec)
+	if err != nil {
+		markTunnelConditionFalse(tunnel, cloudflaredv1alpha1.CredentialsReadyCondition, cloudflaredv1alpha1.ReconcileFailedReason, err.Error())
+		return ctrl.Result{}, err
+	}
+
 	if !tunnel.DeletionTimestamp.IsZero() {
-		return r.reconcileDelete(ctx, cfclient, tunnel)
+		result, err := r.reconcileDelete(ctx, cfclient, tunnel)
+		if err != nil {
+			r.recordTunnelWarning(ctx, tunnel, ReasonDeletionBlocked, "Unable to delete cloudflare tunnel: %v", err)
+		}
+
+		return result, err
+	}
+
+	result, err := r.reconcile(ctx, cfclient, tunnel)
+	if err != nil {
+		r.recordTunnelWarning(ctx, tunnel, ReasonReconcileFailed, "Unable to reconcile tunnel: %v", err)
 	}
 
-	return r.reconcile(ctx, cfclient, tunnel)
+	return result, err
 }
 
-func (r *TunnelReconciler) reconcile(ctx context.Context, cfclient cloudflare.Client, tunnel *cloudflaredv1alpha1.Tunnel) (ctrl.Result, error) {
+func (r *TunnelReconciler) reconcile(ctx context.Context, cfclient cloudflare.Client, tunnel *cloudflaredv1alpha1.Tunnel) (_ ctrl.Result, reterr error) {
 	log := log.FromContext(ctx)
 	controllerutil.AddFinalizer(tunnel, cloudflaredv1alpha1.TunnelFinalizer)
 	log.Info("Reconciling")
 
+	// condition is the condition of the phase being reconciled, it turns false when the phase fails.
+	condition := cloudflaredv1alpha1.CloudTunnelReadyCondition
+	defer func() {
+		if reterr != nil {
+			markTunnelConditionFalse(tunnel, condition, cloudflaredv1alpha1.ReconcileFailedReason, reterr.Error())
+		}
+	}()
+
 	tr := resources.NewTunnelResources(tunnel)
 	cftunnelName := tr.TunnelName()
 
-	log.Info("Ensuring cloudflare zone")
-	cfzone, err := cfclient.Zones().Get(ctx, cfclient.ZoneID())
+	log.Info("Ensuring cloudflare tunnel")
+	cftunnel, err := r.getCloudTunnel(ctx, cfclient, tunnel)
+	switch {
+	case cloudflare.IsNotFound(err):
+		log.Info("Creating new cloudflare tunnel")
+		cftunnel, err = cfclient.Tunnels().Create(ctx, cftunnelName)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		// Record the tunnel right away, so it is adopted rather than created again when a later step fails.
+		setCloudTunnelStatus(tunnel, cftunnel)
+		r.Recorder.Eventf(tunnel, corev1.EventTypeNormal, ReasonCloudTunnelCreated, "Created cloudflare tunnel %s", cftunnel.ID)
+	case err != nil:
+		return ctrl.Result{}, err
+	case tunnel.Status.TunnelID != cftunnel.ID.String():
+		r.Recorder.Eventf(tunnel, corev1.EventTypeNormal, ReasonCloudTunnelAdopted, "Adopted existing cloudflare tunnel %s", cftunnel.ID)
+	}
+
+	setCloudTunnelStatus(tunnel, cftunnel)
+	markTunnelConditionTrue(tunnel, cloudflaredv1alpha1.CloudTunnelReadyCondition)
+
+	condition = cloudflaredv1alpha1.CredentialsReadyCondition
+	// Only the Secret mounted into cloudflared is created.
+	if tr.RunsWithCredentialsFile() {
+		log.Info("Ensuring tunnel credentials secret")
+		err = r.reconcileCredentialsSecret(ctx, cfclient, tunnel, cftunnel.ID)
+	} else {
+		log.Info("Ensuring tunnel token secret")
+		err = r.reconcileTokenSecret(ctx, cfclient, tunnel, cftunnel.ID)
+	}
+
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
-	tunnel.Status.Zone = cfzone.Name
+	markTunnelConditionTrue(tunnel, cloudflaredv1alpha1.CredentialsReadyCondition)
 
-	log.Info("Ensuring cloudflare tunnel")
-	cftunnel, err := cfclient.Tunnels().GetByName(ctx, cftunnelName)
+	condition = cloudflaredv1alpha1.RoutesReadyCondition
+	if err := r.reconcileRoutes(ctx, cfclient, tunnel, cftunnel.ID); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	markTunnelConditionTrue(tunnel, cloudflaredv1alpha1.RoutesReadyCondition)
+
+	condition = cloudflaredv1alpha1.ConfigRenderedCondition
+	log.Info("Ensuring tunnel network routes")
+	networkRoutes, err := r.tunnelNetworkRoutes(ctx, tunnel)
 	if err != nil {
-		if err != cloudflare.ErrNotFound {
+		return ctrl.Result{}, err
+	}
+
+	tunnel.Status.NetworkRoutes = networkRoutes
+
+	// An invalid configuration would crash cloudflared, so the previous configuration
+	// and the running cloudflared are left as they are until the configuration is fixed.
+	if err := tr.ValidateConfiguration(); err != nil {
+		log.Info("Keeping previous tunnel configuration", "error", err.Error())
+		markTunnelConditionFalse(tunnel, cloudflaredv1alpha1.ConfigRenderedCondition, cloudflaredv1alpha1.InvalidConfigReason, err.Error())
+		r.recordTunnelWarning(ctx, tunnel, ReasonInvalidConfig, "Invalid tunnel configuration: %v", err)
+	} else {
+		if err := r.reconcileConfiguration(ctx, cfclient, tunnel, cftunnel.ID); err != nil {
 			return ctrl.Result{}, err
 		}
 
-		log.Info("Creating new cloudflare tunnel")
-		cftunnel, err = cfclient.Tunnels().Create(ctx, cftunnelName)
-		if err != nil {
+		markTunnelConditionTrue(tunnel, cloudflaredv1alpha1.ConfigRenderedCondition)
+	}
+
+	condition = cloudflaredv1alpha1.ConnectorsAvailableCondition
+	log.Info("Observing tunnel connectors")
+	connectors, err := cfclient.Tunnels().Connections(ctx, cftunnel.ID)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	tunnel.Status.Connectors = connectorStatus(connectors)
+	tunnel.Status.ConnectorCount = int32(len(tunnel.Status.Connectors))
+	if lastSeen := connectorsLastSeen(connectors); lastSeen != nil {
+		tunnel.Status.LastSeen = lastSeen
+	}
+
+	if tunnel.Status.ConnectorCount > 0 {
+		markTunnelConditionTrue(tunnel, cloudflaredv1alpha1.ConnectorsAvailableCondition)
+	} else {
+		markTunnelConditionFalse(tunnel, cloudflaredv1alpha1.ConnectorsAvailableCondition, cloudflaredv1alpha1.WaitingForConnectorsReason,
+			"No cloudflared connector is connected to the cloudflare edge yet")
+	}
+
+	tunnel.Status.ObservedGeneration = tunnel.Generation
+
+	// Requeue periodically to keep the observed connectors fresh.
+	return ctrl.Result{RequeueAfter: r.ResyncPeriod}, nil
+}
+
+func (r *TunnelReconciler) reconcileDelete(ctx context.Context, cfclient cloudflare.Client, tunnel *cloudflaredv1alpha1.Tunnel) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	log.Info("Reconciling to deleting")
+
+	tr := resources.NewTunnelResources(tunnel)
+
+	log.Info("Ensuring all tunnel daemon has stopped")
+	dep := tr.Deployment()
+	if err := r.Client.Get(ctx, client.ObjectKeyFromObject(dep), dep); err != nil && !apierrors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+
+	if *dep.Spec.Replicas != 0 {
+		log.Info("Scaling tunnel daemon to 0")
+		depPatch := client.MergeFrom(dep.DeepCopyObject())
+		dep.Spec.Replicas = pointer.Int32Ptr(0)
+		// Stopping the cloudflared tunnel daemon by scaling dep to 0
+		// so cloudflared tunnel will be gracefully shutting down and hopefully closing all connections.
+		if err := r.Client.Patch(ctx, dep, depPatch); err != nil {
 			return ctrl.Result{}, err
 		}
 
-		log.Info("Creating tunnel secret")
-		secretData := make(map[string][]byte)
-		secretDataKey := cftunnelName + ".json"
-		secretDataValue, err := json.Marshal(cftunnel.CredentialsFile)
-		if err != nil {
-			// should we delete the cloudflare tunnel?
+		r.Recorder.Eventf(tunnel, corev1.EventTypeNormal, ReasonScaledDown, "Scaled down tunnel daemon %s to 0", dep.Name)
+	}
+
+	// Only the pods metadata is needed, so avoid caching the whole pods of the cluster.
+	pods := &metav1.PartialObjectMetadataList{}
+	pods.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("PodList"))
+	if err := r.Client.List(ctx, pods, client.InNamespace(tunnel.Namespace), client.MatchingLabels(tr.CommonLabels())); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if len(pods.Items) != 0 {
+		log.Info("Waiting for tunnel daemon pods to terminate", "pods", len(pods.Items))
+		// Record the event only once when the wait starts rather than on every poll.
+		if condition := meta.FindStatusCondition(tunnel.Status.Conditions, cloudflaredv1alpha1.ConnectorsAvailableCondition); condition == nil ||
+			condition.Reason != cloudflaredv1alpha1.TerminatingConnectorsReason {
+			r.Recorder.Eventf(tunnel, corev1.EventTypeNormal, ReasonWaitingForConnectors, "Waiting for %d tunnel daemon pods to terminate", len(pods.Items))
+		}
+
+		markTunnelConditionFalse(tunnel, cloudflaredv1alpha1.ConnectorsAvailableCondition, cloudflaredv1alpha1.TerminatingConnectorsReason,
+			fmt.Sprintf("Waiting for %d tunnel daemon pods to terminate", len(pods.Items)))
+		return ctrl.Result{RequeueAfter: connectorTerminationPollPeriod}, nil
+	}
+
+	log.Info("Deleting cloudflare tunnel")
+	cftunnel, err := r.getCloudTunnel(ctx, cfclient, tunnel)
+	switch {
+	case err == nil:
+		log.Info("Cleaning up cloudflare tunnel connections")
+		// The connections of terminated connectors could linger for a while
+		// and cloudflare refuses to delete a tunnel having connections.
+		if err := cfclient.Tunnels().CleanupConnections(ctx, cftunnel.ID); err != nil && !cloudflare.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+
+		r.Recorder.Eventf(tunnel, corev1.EventTypeNormal, ReasonConnectionsCleanedUp, "Cleaned up connections of cloudflare tunnel %s", cftunnel.ID)
+
+		log.Info("Removing cloudflare tunnel routes")
+		if err := r.removeTunnelRoutes(ctx, cfclient, tunnel, cftunnel.ID); err != nil {
 			return ctrl.Result{}, err
 		}
 
-		secretData[secretDataKey] = secretDataValue
-		secret := tr.Secret(secretData)
-		if err := controllerutil.SetControllerReference(tunnel, secret, r.Scheme); err != nil {
+		log.Info("Removing cloudflare tunnel network routes")
+		if err := r.removeNetworkRoutes(ctx, cfclient, tunnel, cftunnel.ID); err != nil {
 			return ctrl.Result{}, err
 		}
-		if err := r.Client.Create(ctx, secret); err != nil {
+
+		if err := cfclient.Tunnels().Delete(ctx, cftunnel.ID); err != nil && !cloudflare.IsNotFound(err) {
 			return ctrl.Result{}, err
 		}
+
+		r.Recorder.Eventf(tunnel, corev1.EventTypeNormal, ReasonCloudTunnelDeleted, "Deleted cloudflare tunnel %s", cftunnel.ID)
+	case cloudflare.IsNotFound(err):
+		log.Info("Cloudflare tunnel not found or already deleted")
+	default:
+		return ctrl.Result{}, err
+	}
+
+	ConfigRolloutsTotal.DeleteLabelValues(tunnel.Namespace, tunnel.Name)
+	controllerutil.RemoveFinalizer(tunnel, cloudflaredv1alpha1.TunnelFinalizer)
+	return ctrl.Result{}, nil
+}
+
+// reconcileRoutes routes the hostnames of the Tunnel through the cloudflare tunnel, from the DNS records
+// of their zones or the Load Balancer, and unroutes the hostnames removed from the spec.
+func (r *TunnelReconciler) reconcileRoutes(ctx context.Context, cfclient cloudflare.Client, tunnel *cloudflaredv1alpha1.Tunnel, tunnelID uuid.UUID) error {
+	log := log.FromContext(ctx)
+	log.Info("Ensuring cloudflare zones")
+	cfzones, err := r.tunnelZones(ctx, cfclient)
+	if err != nil {
+		return err
 	}
 
 	log.Info("Ensuring cloudflare tunnel route")
-	desiredRoutes := tunnelroutes.FromTunnelSpec(tunnel.Spec)
+	hostnames := tunnelroutes.FromTunnelSpec(tunnel.Spec)
 	// If we got empty routes from TunnelSpec
 	// then use the zone name (root domain).
-	if len(desiredRoutes) == 0 {
-		desiredRoutes = append(desiredRoutes, cfzone.Name)
+	if len(hostnames) == 0 {
+		for _, cfzone := range cfzones {
+			if cfzone.ID == cfclient.ZoneID() {
+				hostnames = append(hostnames, cfzone.Name)
+			}
+		}
+	}
+
+	desiredZones := routeZones(hostnames, cfzones)
+	desiredRoutes := make([]string, 0, len(desiredZones))
+	for _, zone := range desiredZones {
+		desiredRoutes = append(desiredRoutes, zone.Hostname)
+	}
+
+	unmatched := tunnelroutes.Difference(hostnames, desiredRoutes)
+	if len(unmatched) != 0 {
+		log.Info("Skipping hostnames not belonging to any reachable zone", "hostnames", unmatched)
+	}
+
+	firstRouting := len(tunnel.Status.Routes) == 0
+
+	// Switching between DNS and Load Balancer routing, or moving to another
+	// Load Balancer pool, starts over by removing the previous routes.
+	if loadBalancerPoolChanged(tunnel) {
+		log.Info("Removing previous tunnel routes")
+		if err := r.removeTunnelRoutes(ctx, cfclient, tunnel, tunnelID); err != nil {
+			return err
+		}
 	}
 
+	// A hostname moving to another zone, e.g. a more specific zone gets added,
+	// is routed again from the new zone.
 	actualRoutes := tunnelroutes.FromTunnelStatus(tunnel.Status)
+	desiredZoneIDs := make(map[string]string, len(desiredZones))
+	for _, zone := range desiredZones {
+		desiredZoneIDs[zone.Hostname] = zone.ZoneID
+	}
+
+	var movedRoutes []string
+	for _, zone := range tunnel.Status.Zones {
+		if zoneID, ok := desiredZoneIDs[zone.Hostname]; ok && zoneID != zone.ZoneID {
+			movedRoutes = append(movedRoutes, zone.Hostname)
+		}
+	}
+
+	for _, hostname := range movedRoutes {
+		if tunnel.Spec.LoadBalancer != nil {
+			continue
+		}
+
+		log.Info("Removing tunnel route of previous zone", "hostname", hostname)
+		if err := r.removeTunnelRoute(ctx, cfclient, tunnel, routeZoneID(cfclient, tunnel.Status, hostname), tunnelID, hostname); err != nil {
+			return err
+		}
+	}
+
+	actualRoutes = tunnelroutes.Difference(actualRoutes, movedRoutes)
 	hostnameNeedRouted := tunnelroutes.Difference(desiredRoutes, actualRoutes)
 	for _, hostname := range hostnameNeedRouted {
-		log.Info("Updating tunnel route")
-		cftunnelRoute := &cloudflare.TunnelDNSRoute{
+		log.Info("Updating tunnel route", "hostname", hostname, "zone-id", desiredZoneIDs[hostname])
+		var cftunnelRoute cloudflare.TunnelRoute = &cloudflare.TunnelDNSRoute{
 			Hostname: hostname,
 		}
+		routeType := RouteTypeDNS
 
-		if err := cfclient.Tunnels().Route(ctx, cftunnel.ID, cftunnelRoute); err != nil {
-			return ctrl.Result{}, err
+		if lb := tunnel.Spec.LoadBalancer; lb != nil {
+			cftunnelRoute = &cloudflare.TunnelLBRoute{
+				LBName: hostname,
+				LBPool: lb.Pool,
+			}
+			routeType = RouteTypeLoadBalancer
+		}
+
+		if err := cfclient.Tunnels().Route(ctx, desiredZoneIDs[hostname], tunnelID, cftunnelRoute); err != nil {
+			RouteFailuresTotal.WithLabelValues(routeType, RouteOperationCreate).Inc()
+			r.Recorder.Eventf(tunnel, corev1.EventTypeWarning, ReasonRouteFailed, "Unable to route %s: %v", hostname, err)
+			return err
 		}
+
+		r.Recorder.Eventf(tunnel, corev1.EventTypeNormal, ReasonRouteAdded, "Routed %s through cloudflare tunnel %s", hostname, tunnelID)
 	}
-	tunnel.Status.Routes = desiredRoutes
 
-	log.Info("Ensuring tunnel secret")
-	secret := &corev1.Secret{}
-	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: tunnel.Namespace, Name: tr.SecretName()}, secret); err != nil {
-		return ctrl.Result{}, err
+	if firstRouting && len(hostnameNeedRouted) != 0 {
+		r.observeIngressRouted(ctx, tunnel)
 	}
 
-	configMap := tr.ConfigMap()
-	configMapOp, err := controllerutil.CreateOrPatch(ctx, r.Client, configMap, func() error {
-		configMap.Data, err = tr.ConfigMapData()
+	// Only the hostnames removed from the spec are unrouted. A hostname without a reachable zone,
+	// e.g. after the permissions of the credentials changed, keeps its route and its status.
+	hostnameNeedUnrouted := tunnelroutes.Difference(actualRoutes, hostnames)
+	for _, hostname := range hostnameNeedUnrouted {
+		if tunnel.Spec.LoadBalancer != nil {
+			// The Load Balancer could be serving Tunnels from other clusters, so leave it as is.
+			log.Info("Leaving load balancer of hostname no longer routed", "hostname", hostname)
+			continue
+		}
+
+		log.Info("Removing tunnel route", "hostname", hostname)
+		if err := r.removeTunnelRoute(ctx, cfclient, tunnel, routeZoneID(cfclient, tunnel.Status, hostname), tunnelID, hostname); err != nil {
+			return err
+		}
+	}
+
+	keptRoutes := sets.NewString(unmatched...).Intersection(sets.NewString(actualRoutes...))
+	for _, zone := range tunnel.Status.Zones {
+		if keptRoutes.Has(zone.Hostname) {
+			desiredZones = append(desiredZones, zone)
+		}
+	}
+
+	tunnel.Status.Routes = append(desiredRoutes, keptRoutes.List()...)
+	tunnel.Status.Zones = desiredZones
+
+	if lb := tunnel.Spec.LoadBalancer; lb != nil {
+		log.Info("Ensuring load balancer pool membership", "pool", lb.Pool)
+		tunnel.Status.LoadBalancer = &cloudflaredv1alpha1.TunnelLoadBalancerStatus{Pool: lb.Pool}
+		lbStatus, err := r.loadBalancerStatus(ctx, cfclient, lb.Pool, tunnelID)
 		if err != nil {
 			return err
 		}
 
-		return controllerutil.SetControllerReference(tunnel, configMap, r.Scheme)
-	})
-	if err != nil {
-		return ctrl.Result{}, err
+		tunnel.Status.LoadBalancer = lbStatus
 	}
 
-	log.Info("Reconcile tunnel configmap", "operation", configMapOp)
+	return nil
+}
+
+// reconcileConfiguration pushes the configuration of remotely managed Tunnel to cloudflare, or renders it
+// into the ConfigMap, and ensures the cloudflared Deployment.
+func (r *TunnelReconciler) reconcileConfiguration(ctx context.Context, cfclient cloudflare.Client, tunnel *cloudflaredv1alpha1.Tunnel, tunnelID uuid.UUID) error {
+	log := log.FromContext(ctx)
+	tr := resources.NewTunnelResources(tunnel)
+
+	// Restart the cloudflared daemon only when the configuration is read from the ConfigMap,
+	// a remotely managed Tunnel gets the configuration changes without restart.
+	var restart bool
+	if tr.IsRemotelyManaged() {
+		log.Info("Updating remote tunnel configuration")
+		config, err := tr.RemoteConfiguration()
+		if err != nil {
+			return err
+		}
+
+		if err := cfclient.Tunnels().UpdateConfiguration(ctx, tunnelID, config); err != nil {
+			return err
+		}
+	} else {
+		configMap := tr.ConfigMap()
+		configMapOp, err := controllerutil.CreateOrPatch(ctx, r.Client, configMap, func() error {
+			data, err := tr.ConfigMapData()
+			if err != nil {
+				return err
+			}
+
+			configMap.Data = data
+			return controllerutil.SetControllerReference(tunnel, configMap, r.Scheme)
+		})
+		if err != nil {
+			return err
+		}
+
+		log.Info("Reconcile tunnel configmap", "operation", configMapOp)
+		restart = configMapOp == controllerutil.OperationResultUpdated
+	}
 
 	dep := tr.Deployment()
 	depOp, err := controllerutil.CreateOrPatch(ctx, r.Client, dep, func() error {
+		// Replace the whole pod template only when the desired one changes, so the
+		// defaulted fields of existing pod template do not cause a patch on every reconcile.
+		desiredTemplate := tr.PodTemplate()
+		if dep.Spec.Template.Annotations[resources.PodTemplateHashAnnotation] != desiredTemplate.Annotations[resources.PodTemplateHashAnnotation] {
+			dep.Spec.Template = desiredTemplate
+		}
+
 		// Restart the cloudflared daemon if the configuration got an update by set an annotations
 		// into PodTemplateSpec to gracefully restart the Pod. So the Tunnel could be use new configurations.
 		// This restart mechanism inspired by kubectl rollout restart
-		if configMapOp == controllerutil.OperationResultUpdated {
-			dep.Spec.Template.SetAnnotations(map[string]string{
-				"cloudflared.cloudflare.com/restarted-at": time.Now().Format(time.RFC3339),
-			})
+		if restart {
+			annotations := dep.Spec.Template.GetAnnotations()
+			if annotations == nil {
+				annotations = make(map[string]string)
+			}
+
+			annotations["cloudflared.cloudflare.com/restarted-at"] = time.Now().Format(time.RFC3339)
+			dep.Spec.Template.SetAnnotations(annotations)
 		}
 
 		dep.Spec.Replicas = pointer.Int32Ptr(1)
 		return controllerutil.SetControllerReference(tunnel, dep, r.Scheme)
 	})
 	if err != nil {
-		return ctrl.Result{}, err
+		return err
 	}
 
 	log.Info("Reconcile tunnel deployment", "operation", depOp)
-	return ctrl.Result{}, nil
+	if restart {
+		ConfigRolloutsTotal.WithLabelValues(tunnel.Namespace, tunnel.Name).Inc()
+		r.Recorder.Eventf(tunnel, corev1.EventTypeNormal, ReasonConfigRolledOut, "Restarted tunnel daemon %s to roll out the updated configuration", dep.Name)
+	}
+
+	return nil
 }
 
-func (r *TunnelReconciler) reconcileDelete(ctx context.Context, cfclient cloudflare.Client, tunnel *cloudflaredv1alpha1.Tunnel) (ctrl.Result, error) {
-	log := log.FromContext(ctx)
-	log.Info("Reconciling to deleting")
+// getCloudTunnel fetches the cloudflare tunnel by the ID recorded in the Tunnel status. The tunnel is looked
+// up by name when no ID has been recorded yet, or the recorded one is gone, to adopt a tunnel created before.
+func (r *TunnelReconciler) getCloudTunnel(ctx context.Context, cfclient cloudflare.Client, tunnel *cloudflaredv1alpha1.Tunnel) (*cloudflare.Tunnel, error) {
+	tr := resources.NewTunnelResources(tunnel)
+	tunnelID, err := uuid.Parse(tunnel.Status.TunnelID)
+	if err != nil {
+		return cfclient.Tunnels().GetByName(ctx, tr.TunnelName())
+	}
+
+	cftunnel, err := cfclient.Tunnels().Get(ctx, tunnelID)
+	switch {
+	// A deleted tunnel is still returned by its ID.
+	case err == nil && cftunnel.DeletedAt.IsZero():
+		return cftunnel, nil
+	case err != nil && !cloudflare.IsNotFound(err):
+		return nil, err
+	}
+
+	log.FromContext(ctx).Info("Recorded cloudflare tunnel is gone, looking it up by name", "tunnel-id", tunnelID.String())
+	cftunnel, err = cfclient.Tunnels().GetByName(ctx, tr.TunnelName())
+	if err != nil {
+		return nil, err
+	}
 
+	// The tunnel fetched by name could be served from the cache of the client.
+	if cftunnel.ID == tunnelID {
+		return nil, cloudflare.ErrNotFound
+	}
+
+	return cftunnel, nil
+}
+
+// reconcileCredentialsSecret ensures the Secret holding the credentials file of the tunnel, built from the
+// tunnel token so an adopted tunnel gets it too. The Secret is immutable, so a Secret left by a previous
+// cloud tunnel of the Tunnel is replaced.
+func (r *TunnelReconciler) reconcileCredentialsSecret(ctx context.Context, cfclient cloudflare.Client, tunnel *cloudflaredv1alpha1.Tunnel, tunnelID uuid.UUID) error {
 	tr := resources.NewTunnelResources(tunnel)
-	cftunnelName := tr.TunnelName()
+	secret := &corev1.Secret{}
+	err := r.Client.Get(ctx, client.ObjectKey{Namespace: tunnel.Namespace, Name: tr.SecretName()}, secret)
+	found := err == nil
+	switch {
+	case found:
+		credentials := &cloudflare.TunnelCredentials{}
+		if json.Unmarshal(secret.Data[tr.CredentialsFileKey()], credentials) == nil &&
+			credentials.TunnelID == tunnelID && credentials.TunnelName == tr.TunnelName() {
+			return nil
+		}
+	case !apierrors.IsNotFound(err):
+		return err
+	}
 
-	log.Info("Ensuring all tunnel daemon has stopped")
-	dep := tr.Deployment()
-	if err := r.Client.Get(ctx, client.ObjectKeyFromObject(dep), dep); err != nil && !apierrors.IsNotFound(err) {
-		return ctrl.Result{}, err
+	token, err := cfclient.Tunnels().Token(ctx, tunnelID)
+	if err != nil {
+		return err
 	}
 
-	if *dep.Spec.Replicas != 0 {
-		log.Info("Scaling tunnel daemon to 0")
-		depPatch := client.MergeFrom(dep.DeepCopyObject())
-		dep.Spec.Replicas = pointer.Int32Ptr(0)
-		// Stopping the cloudflared tunnel daemon by scaling dep to 0
-		// so cloudflared tunnel will be gracefully shutting down and hopefully closing all connections.
-		if err := r.Client.Patch(ctx, dep, depPatch); err != nil {
-			return ctrl.Result{}, err
+	credentials, err := cloudflare.ParseTunnelToken(token)
+	if err != nil {
+		return err
+	}
+
+	// cloudflared resolves the tunnel name of its args from the credentials file.
+	credentials.TunnelName = tr.TunnelName()
+	credentialsData, err := json.Marshal(credentials)
+	if err != nil {
+		return err
+	}
+
+	desired := tr.Secret(map[string][]byte{tr.CredentialsFileKey(): credentialsData})
+	if err := controllerutil.SetControllerReference(tunnel, desired, r.Scheme); err != nil {
+		return err
+	}
+
+	if found {
+		log.FromContext(ctx).Info("Replacing tunnel credentials secret of previous cloudflare tunnel")
+		if err := r.Client.Delete(ctx, secret); err != nil && !apierrors.IsNotFound(err) {
+			return err
 		}
+	}
 
-		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	if err := r.Client.Create(ctx, desired); err != nil {
+		return err
 	}
-	log.Info("Deleting cloudflare tunnel")
-	cftunnel, err := cfclient.Tunnels().GetByName(ctx, cftunnelName)
-	if err != nil && err != cloudflare.ErrNotFound {
-		return ctrl.Result{}, err
+
+	r.Recorder.Eventf(tunnel, corev1.EventTypeNormal, ReasonSecretCreated, "Created tunnel credentials secret %s", desired.Name)
+	return nil
+}
+
+// reconcileTokenSecret ensures the Secret holding the token to run the tunnel. The token is only
+// fetched when the Secret does not exist yet or belongs to a previous cloud tunnel.
+func (r *TunnelReconciler) reconcileTokenSecret(ctx context.Context, cfclient cloudflare.Client, tunnel *cloudflaredv1alpha1.Tunnel, tunnelID uuid.UUID) error {
+	log := log.FromContext(ctx)
+	tr := resources.NewTunnelResources(tunnel)
+	secret := &corev1.Secret{}
+	err := r.Client.Get(ctx, client.ObjectKey{Namespace: tunnel.Namespace, Name: tr.TokenSecretName()}, secret)
+	switch {
+	case err == nil && secret.Annotations[resources.TunnelIDAnnotation] == tunnelID.String():
+		return nil
+	case err != nil && !apierrors.IsNotFound(err):
+		return err
 	}
 
-	if err := cfclient.Tunnels().Delete(ctx, cftunnel.ID); err != nil && err != cloudflare.ErrNotFound {
-		return ctrl.Result{}, err
+	token, err := cfclient.Tunnels().Token(ctx, tunnelID)
+	if err != nil {
+		return err
 	}
 
-	controllerutil.RemoveFinalizer(tunnel, cloudflaredv1alpha1.TunnelFinalizer)
-	return ctrl.Result{}, nil
+	desired := tr.TokenSecret(tunnelID.String(), token)
+	secret = tr.TokenSecret(tunnelID.String(), token)
+	op, err := controllerutil.CreateOrPatch(ctx, r.Client, secret, func() error {
+		secret.Annotations = labels.Merge(secret.Annotations, desired.Annotations)
+		secret.Data = desired.Data
+		return controllerutil.SetControllerReference(tunnel, secret, r.Scheme)
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Info("Reconcile tunnel token secret", "operation", op)
+	if op == controllerutil.OperationResultCreated {
+		r.Recorder.Eventf(tunnel, corev1.EventTypeNormal, ReasonSecretCreated, "Created tunnel token secret %s", secret.Name)
+	}
+
+	return nil
+}
+
+// tunnelNetworkRoutes returns the sorted CIDRs of TunnelNetworkRoutes referencing the Tunnel.
+func (r *TunnelReconciler) tunnelNetworkRoutes(ctx context.Context, tunnel *cloudflaredv1alpha1.Tunnel) ([]string, error) {
+	routeList := &cloudflaredv1alpha1.TunnelNetworkRouteList{}
+	if err := r.Client.List(ctx, routeList, client.InNamespace(tunnel.Namespace)); err != nil {
+		return nil, err
+	}
+
+	cidrs := sets.NewString()
+	for _, route := range routeList.Items {
+		if route.Spec.TunnelRef.Name == tunnel.Name && route.DeletionTimestamp.IsZero() {
+			cidrs.Insert(route.Spec.CIDRs...)
+		}
+	}
+
+	return cidrs.List(), nil
+}
+
+// removeTunnelRoutes removes every route recorded in the Tunnel status, either the DNS records
+// or the Tunnel origin from the Load Balancer pool.
+func (r *TunnelReconciler) removeTunnelRoutes(ctx context.Context, cfclient cloudflare.Client, tunnel *cloudflaredv1alpha1.Tunnel, tunnelID uuid.UUID) error {
+	if lb := tunnel.Status.LoadBalancer; lb != nil {
+		if err := r.removeTunnelFromPool(ctx, cfclient, lb.Pool, tunnelID); err != nil {
+			return err
+		}
+	} else {
+		for _, hostname := range tunnelroutes.FromTunnelStatus(tunnel.Status) {
+			if err := r.removeTunnelRoute(ctx, cfclient, tunnel, routeZoneID(cfclient, tunnel.Status, hostname), tunnelID, hostname); err != nil {
+				return err
+			}
+		}
+	}
+
+	tunnel.Status.Routes = nil
+	tunnel.Status.Zones = nil
+	tunnel.Status.LoadBalancer = nil
+	return nil
+}
+
+// removeTunnelRoute deletes the DNS records of given hostname in the zone. Only CNAME records
+// that point to the tunnel are deleted, so records owned by others are left untouched.
+func (r *TunnelReconciler) removeTunnelRoute(ctx context.Context, cfclient cloudflare.Client, tunnel *cloudflaredv1alpha1.Tunnel, zoneID string, tunnelID uuid.UUID, hostname string) error {
+	log := log.FromContext(ctx)
+	records, err := cfclient.DNSRecords().List(ctx, zoneID, &cloudflare.DNSRecordListOptions{
+		Type:    cloudflare.DNSRecordTypeCNAME,
+		Name:    hostname,
+		Content: cloudflare.TunnelHostname(tunnelID),
+	})
+	if err != nil {
+		RouteFailuresTotal.WithLabelValues(RouteTypeDNS, RouteOperationDelete).Inc()
+		r.Recorder.Eventf(tunnel, corev1.EventTypeWarning, ReasonRouteFailed, "Unable to remove route of %s: %v", hostname, err)
+		return err
+	}
+
+	for _, record := range records {
+		log.Info("Deleting DNS record", "hostname", record.Name, "record-id", record.ID)
+		if err := cfclient.DNSRecords().Delete(ctx, zoneID, record.ID); err != nil && !cloudflare.IsNotFound(err) {
+			RouteFailuresTotal.WithLabelValues(RouteTypeDNS, RouteOperationDelete).Inc()
+			r.Recorder.Eventf(tunnel, corev1.EventTypeWarning, ReasonRouteFailed, "Unable to remove route of %s: %v", hostname, err)
+			return err
+		}
+
+		r.Recorder.Eventf(tunnel, corev1.EventTypeNormal, ReasonRouteRemoved, "Removed route of %s", record.Name)
+	}
+
+	return nil
+}
+
+// tunnelZones returns the zones of the account reachable by the credentials. The zone of
+// the client is always included, even when the credentials are not allowed to list zones.
+func (r *TunnelReconciler) tunnelZones(ctx context.Context, cfclient cloudflare.Client) ([]*cloudflare.Zone, error) {
+	cfzones, err := cfclient.Zones().List(ctx, &cloudflare.ZoneListOptions{AccountID: cfclient.AccountID()})
+	if err != nil && !cloudflare.IsUnauthorized(err) {
+		return nil, err
+	}
+
+	for _, cfzone := range cfzones {
+		if cfzone.ID == cfclient.ZoneID() {
+			return cfzones, nil
+		}
+	}
+
+	cfzone, err := cfclient.Zones().Get(ctx, cfclient.ZoneID())
+	if err != nil {
+		return nil, err
+	}
+
+	return append(cfzones, cfzone), nil
+}
+
+// routeZones matches each hostname to its zone by the longest suffix. Hostnames that do not
+// belong to any of the zones are left out.
+func routeZones(hostnames []string, cfzones []*cloudflare.Zone) []cloudflaredv1alpha1.TunnelRouteZone {
+	zoneNames := make([]string, 0, len(cfzones))
+	zoneIDs := make(map[string]string, len(cfzones))
+	for _, cfzone := range cfzones {
+		zoneNames = append(zoneNames, cfzone.Name)
+		zoneIDs[cfzone.Name] = cfzone.ID
+	}
+
+	var result []cloudflaredv1alpha1.TunnelRouteZone
+	for _, hostname := range hostnames {
+		zoneName := tunnelroutes.ZoneOf(hostname, zoneNames)
+		if zoneName == "" {
+			continue
+		}
+
+		result = append(result, cloudflaredv1alpha1.TunnelRouteZone{
+			Hostname: hostname,
+			Zone:     zoneName,
+			ZoneID:   zoneIDs[zoneName],
+		})
+	}
+
+	return result
+}
+
+// removeNetworkRoutes removes the private network routes through the cloudflare tunnel, so the routes
+// of TunnelNetworkRoutes outliving the Tunnel are not left behind without credentials to remove them.
+func (r *TunnelReconciler) removeNetworkRoutes(ctx context.Context, cfclient cloudflare.Client, tunnel *cloudflaredv1alpha1.Tunnel, tunnelID uuid.UUID) error {
+	cfroutes, err := cfclient.NetworkRoutes().List(ctx, &cloudflare.NetworkRouteListOptions{
+		TunnelID:  tunnelID.String(),
+		IsDeleted: pointer.BoolPtr(false),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, cfroute := range cfroutes {
+		if err := cfclient.NetworkRoutes().Delete(ctx, cfroute.ID); err != nil && !cloudflare.IsNotFound(err) {
+			RouteFailuresTotal.WithLabelValues(RouteTypeNetwork, RouteOperationDelete).Inc()
+			return err
+		}
+
+		r.Recorder.Eventf(tunnel, corev1.EventTypeNormal, ReasonNetworkRouteDeleted, "Removed route of %s", cfroute.Network)
+	}
+
+	return nil
+}
+
+// routeZoneID returns the zone ID of the registered route. Routes registered before
+// the zone got recorded into the Tunnel status belong to the zone of the client.
+func routeZoneID(cfclient cloudflare.Client, status cloudflaredv1alpha1.TunnelStatus, hostname string) string {
+	for _, zone := range status.Zones {
+		if zone.Hostname == hostname {
+			return zone.ZoneID
+		}
+	}
+
+	return cfclient.ZoneID()
+}
+
+// removeTunnelFromPool removes the Tunnel origin from the Load Balancer pool. When the Tunnel
+// is the only origin, the origin is disabled instead since a pool requires at least one origin.
+func (r *TunnelReconciler) removeTunnelFromPool(ctx context.Context, cfclient cloudflare.Client, poolName string, tunnelID uuid.UUID) error {
+	log := log.FromContext(ctx)
+	pool, err := cfclient.LoadBalancers().GetPoolByName(ctx, poolName)
+	if err != nil {
+		if cloudflare.IsNotFound(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	address := cloudflare.TunnelHostname(tunnelID)
+	origins := make([]cloudflare.LoadBalancerOrigin, 0, len(pool.Origins))
+	for _, origin := range pool.Origins {
+		if origin.Address != address {
+			origins = append(origins, origin)
+		}
+	}
+
+	switch len(origins) {
+	case len(pool.Origins):
+		return nil
+	case 0:
+		log.Info("Disabling tunnel origin of load balancer pool", "pool", poolName)
+		for i := range pool.Origins {
+			pool.Origins[i].Enabled = false
+		}
+	default:
+		log.Info("Removing tunnel origin from load balancer pool", "pool", poolName)
+		pool.Origins = origins
+	}
+
+	if _, err := cfclient.LoadBalancers().UpdatePoolOrigins(ctx, pool.ID, pool.Origins); err != nil {
+		RouteFailuresTotal.WithLabelValues(RouteTypeLoadBalancer, RouteOperationDelete).Inc()
+		return err
+	}
+
+	return nil
+}
+
+// observeIngressRouted observes the time from the creation of Ingress owning the Tunnel
+// until its hostnames got routed. Tunnels not owned by an Ingress are not observed.
+func (r *TunnelReconciler) observeIngressRouted(ctx context.Context, tunnel *cloudflaredv1alpha1.Tunnel) {
+	owner := metav1.GetControllerOf(tunnel)
+	if owner == nil || owner.Kind != "Ingress" {
+		return
+	}
+
+	ing := &networkingv1.Ingress{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: tunnel.Namespace, Name: owner.Name}, ing); err != nil {
+		log.FromContext(ctx).V(1).Info("Unable to observe ingress routed duration", "error", err.Error())
+		return
+	}
+
+	IngressRoutedDuration.Observe(time.Since(ing.CreationTimestamp.Time).Seconds())
+}
+
+// recordTunnelWarning records a Warning event on the Tunnel, and on the Ingress owning the Tunnel
+// so the failure shows up where the Ingress is managed.
+func (r *TunnelReconciler) recordTunnelWarning(ctx context.Context, tunnel *cloudflaredv1alpha1.Tunnel, reason, messageFmt string, args ...interface{}) {
+	r.Recorder.Eventf(tunnel, corev1.EventTypeWarning, reason, messageFmt, args...)
+	owner := metav1.GetControllerOf(tunnel)
+	if owner == nil || owner.Kind != "Ingress" {
+		return
+	}
+
+	ing := &networkingv1.Ingress{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: tunnel.Namespace, Name: owner.Name}, ing); err != nil {
+		log.FromContext(ctx).V(1).Info("Unable to record event on ingress", "error", err.Error())
+		return
+	}
+
+	r.Recorder.Eventf(ing, corev1.EventTypeWarning, ReasonTunnelFailed, "Tunnel %s: %s", tunnel.Name, fmt.Sprintf(messageFmt, args...))
+}
+
+// loadBalancerStatus returns the Load Balancer pool membership of the Tunnel.
+func (r *TunnelReconciler) loadBalancerStatus(ctx context.Context, cfclient cloudflare.Client, poolName string, tunnelID uuid.UUID) (*cloudflaredv1alpha1.TunnelLoadBalancerStatus, error) {
+	status := &cloudflaredv1alpha1.TunnelLoadBalancerStatus{Pool: poolName}
+	pool, err := cfclient.LoadBalancers().GetPoolByName(ctx, poolName)
+	if err != nil {
+		if cloudflare.IsNotFound(err) {
+			return status, nil
+		}
+
+		return nil, err
+	}
+
+	status.PoolID = pool.ID
+	address := cloudflare.TunnelHostname(tunnelID)
+	for _, origin := range pool.Origins {
+		if origin.Address == address {
+			status.Origin = origin.Name
+			status.Enabled = origin.Enabled
+			break
+		}
+	}
+
+	return status, nil
+}
+
+// loadBalancerPoolChanged reports whether the Tunnel routes are switching between DNS and
+// Load Balancer routing, or moving to another Load Balancer pool.
+func loadBalancerPoolChanged(tunnel *cloudflaredv1alpha1.Tunnel) bool {
+	var desiredPool, actualPool string
+	if tunnel.Spec.LoadBalancer != nil {
+		desiredPool = tunnel.Spec.LoadBalancer.Pool
+	}
+
+	if tunnel.Status.LoadBalancer != nil {
+		actualPool = tunnel.Status.LoadBalancer.Pool
+	}
+
+	return desiredPool != actualPool && len(tunnel.Status.Routes) != 0
+}
+
+// connectorStatus converts the tunnel connectors having active connections into Tunnel status.
+func connectorStatus(connectors []*cloudflare.TunnelConnector) []cloudflaredv1alpha1.TunnelConnectorStatus {
+	var result []cloudflaredv1alpha1.TunnelConnectorStatus
+	for _, connector := range connectors {
+		colos := sets.NewString()
+		for _, conn := range connector.Connections {
+			colos.Insert(conn.ColoName)
+		}
+
+		if colos.Len() == 0 {
+			continue
+		}
+
+		runAt := metav1.NewTime(connector.RunAt)
+		result = append(result, cloudflaredv1alpha1.TunnelConnectorStatus{
+			ID:      connector.ID.String(),
+			Version: connector.Version,
+			Colos:   colos.List(),
+			RunAt:   &runAt,
+		})
+	}
+
+	return result
+}
+
+// connectorsLastSeen returns the latest time a connector connected to the cloudflare edge, or nil
+// when no connector is connected. It is derived from the connections rather than the poll time, so
+// the status only changes when the connectors reconnect.
+func connectorsLastSeen(connectors []*cloudflare.TunnelConnector) *metav1.Time {
+	var lastSeen time.Time
+	for _, connector := range connectors {
+		for _, conn := range connector.Connections {
+			openedAt := conn.OpenedAt
+			if openedAt.IsZero() {
+				openedAt = connector.RunAt
+			}
+
+			if openedAt.After(lastSeen) {
+				lastSeen = openedAt
+			}
+		}
+	}
+
+	if lastSeen.IsZero() {
+		return nil
+	}
+
+	result := metav1.NewTime(lastSeen)
+	return &result
+}
+
+// setCloudTunnelStatus records the cloudflare tunnel in the Tunnel status.
+func setCloudTunnelStatus(tunnel *cloudflaredv1alpha1.Tunnel, cftunnel *cloudflare.Tunnel) {
+	tunnel.Status.TunnelID = cftunnel.ID.String()
+	tunnel.Status.Target = cloudflare.TunnelHostname(cftunnel.ID)
+	createdAt := metav1.NewTime(cftunnel.CreatedAt)
+	tunnel.Status.CreatedAt = &createdAt
+}
+
+// markTunnelConditionTrue marks the condition of Tunnel true at its current generation.
+func markTunnelConditionTrue(tunnel *cloudflaredv1alpha1.Tunnel, conditionType string) {
+	meta.SetStatusCondition(&tunnel.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: tunnel.Generation,
+		Reason:             cloudflaredv1alpha1.ReconciledReason,
+	})
+}
+
+// markTunnelConditionFalse marks the condition of Tunnel false at its current generation.
+func markTunnelConditionFalse(tunnel *cloudflaredv1alpha1.Tunnel, conditionType, reason, message string) {
+	meta.SetStatusCondition(&tunnel.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: tunnel.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// tunnelConditionTypes are the conditions of each phase of Tunnel reconcile, in order.
+var tunnelConditionTypes = []string{
+	cloudflaredv1alpha1.CloudTunnelReadyCondition,
+	cloudflaredv1alpha1.CredentialsReadyCondition,
+	cloudflaredv1alpha1.RoutesReadyCondition,
+	cloudflaredv1alpha1.ConfigRenderedCondition,
+	cloudflaredv1alpha1.ConnectorsAvailableCondition,
+}
+
+// setTunnelReadyCondition sets the Ready condition of Tunnel from the conditions of each phase. Tunnel is
+// not ready while being deleted, or when any phase is not true, in which case the first one is reported.
+func setTunnelReadyCondition(tunnel *cloudflaredv1alpha1.Tunnel) {
+	if !tunnel.DeletionTimestamp.IsZero() {
+		markTunnelConditionFalse(tunnel, cloudflaredv1alpha1.ReadyCondition, cloudflaredv1alpha1.DeletingReason, "Tunnel is being deleted")
+		return
+	}
+
+	for _, conditionType := range tunnelConditionTypes {
+		condition := meta.FindStatusCondition(tunnel.Status.Conditions, conditionType)
+		switch {
+		case condition == nil:
+			meta.SetStatusCondition(&tunnel.Status.Conditions, metav1.Condition{
+				Type:               cloudflaredv1alpha1.ReadyCondition,
+				Status:             metav1.ConditionUnknown,
+				ObservedGeneration: tunnel.Generation,
+				Reason:             cloudflaredv1alpha1.ReconcilingReason,
+				Message:            fmt.Sprintf("%s is not observed yet", conditionType),
+			})
+			return
+		case condition.Status != metav1.ConditionTrue:
+			markTunnelConditionFalse(tunnel, cloudflaredv1alpha1.ReadyCondition, condition.Reason, fmt.Sprintf("%s: %s", conditionType, condition.Message))
+			return
+		}
+	}
+
+	markTunnelConditionTrue(tunnel, cloudflaredv1alpha1.ReadyCondition)
 }
@@ -0,0 +1,755 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	cloudflaredv1alpha1 "github.com/prksu/cloudflared-controller/api/v1alpha1"
+	"github.com/prksu/cloudflared-controller/cloudflare"
+	"github.com/prksu/cloudflared-controller/util/resources"
+)
+
+// newTestCloudflareClient returns a cloudflare client of test-account and test-zone served by given handler.
+func newTestCloudflareClient(t *testing.T, handler http.Handler) cloudflare.Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	cfclient, err := cloudflare.NewClient(
+		cloudflare.WithBaseURL(srv.URL+"/client/v4"),
+		cloudflare.WithHTTPClient(srv.Client()),
+		cloudflare.WithAccountID("test-account"),
+		cloudflare.WithZoneID("test-zone"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cfclient
+}
+
+// writeCloudflareResponse writes the cloudflare api response of given result, or a not found error when it is nil.
+func writeCloudflareResponse(w http.ResponseWriter, result interface{}) {
+	if result == nil {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(cloudflare.Response{Errors: []cloudflare.Error{{Code: "1003", Message: "not found"}}})
+		return
+	}
+
+	raw, _ := json.Marshal(result)
+	_ = json.NewEncoder(w).Encode(cloudflare.Response{Success: true, Result: raw})
+}
+
+// newTestScheme returns the scheme of the kubernetes and cloudflared types.
+func newTestScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(cloudflaredv1alpha1.AddToScheme(scheme))
+	return scheme
+}
+
+func TestSetTunnelReadyCondition(t *testing.T) {
+	allTrue := func() []metav1.Condition {
+		var conditions []metav1.Condition
+		for _, conditionType := range tunnelConditionTypes {
+			conditions = append(conditions, metav1.Condition{Type: conditionType, Status: metav1.ConditionTrue, Reason: cloudflaredv1alpha1.ReconciledReason})
+		}
+
+		return conditions
+	}
+	tests := []struct {
+		name       string
+		tunnel     *cloudflaredv1alpha1.Tunnel
+		wantStatus metav1.ConditionStatus
+		wantReason string
+	}{
+		{
+			name: "every phase is true",
+			tunnel: &cloudflaredv1alpha1.Tunnel{
+				Status: cloudflaredv1alpha1.TunnelStatus{Conditions: allTrue()},
+			},
+			wantStatus: metav1.ConditionTrue,
+			wantReason: cloudflaredv1alpha1.ReconciledReason,
+		},
+		{
+			name:       "phase not observed yet",
+			tunnel:     &cloudflaredv1alpha1.Tunnel{},
+			wantStatus: metav1.ConditionUnknown,
+			wantReason: cloudflaredv1alpha1.ReconcilingReason,
+		},
+		{
+			name: "phase failed",
+			tunnel: func() *cloudflaredv1alpha1.Tunnel {
+				tunnel := &cloudflaredv1alpha1.Tunnel{
+					Status: cloudflaredv1alpha1.TunnelStatus{Conditions: allTrue()},
+				}
+				markTunnelConditionFalse(tunnel, cloudflaredv1alpha1.RoutesReadyCondition, cloudflaredv1alpha1.ReconcileFailedReason, "boom")
+				return tunnel
+			}(),
+			wantStatus: metav1.ConditionFalse,
+			wantReason: cloudflaredv1alpha1.ReconcileFailedReason,
+		},
+		{
+			name: "deleting",
+			tunnel: &cloudflaredv1alpha1.Tunnel{
+				ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: func() *metav1.Time { now := metav1.Now(); return &now }()},
+				Status:     cloudflaredv1alpha1.TunnelStatus{Conditions: allTrue()},
+			},
+			wantStatus: metav1.ConditionFalse,
+			wantReason: cloudflaredv1alpha1.DeletingReason,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setTunnelReadyCondition(tt.tunnel)
+			got := meta.FindStatusCondition(tt.tunnel.Status.Conditions, cloudflaredv1alpha1.ReadyCondition)
+			if got == nil || got.Status != tt.wantStatus || got.Reason != tt.wantReason {
+				t.Errorf("setTunnelReadyCondition() = %v, want status %v reason %v", got, tt.wantStatus, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestConnectorStatus(t *testing.T) {
+	runAt := time.Date(2022, 2, 1, 10, 0, 0, 0, time.UTC)
+	connected := &cloudflare.TunnelConnector{
+		ID:      uuid.MustParse("1bedc50d-42b3-473c-b108-ff3d10c0d925"),
+		Version: "2022.2.0",
+		RunAt:   runAt,
+		Connections: []cloudflare.TunnelConnection{
+			{ColoName: "SIN"},
+			{ColoName: "CGK"},
+			{ColoName: "SIN"},
+		},
+	}
+	disconnected := &cloudflare.TunnelConnector{ID: uuid.New(), Version: "2022.2.0", RunAt: runAt}
+
+	got := connectorStatus([]*cloudflare.TunnelConnector{connected, disconnected})
+	want := []cloudflaredv1alpha1.TunnelConnectorStatus{
+		{
+			ID:      "1bedc50d-42b3-473c-b108-ff3d10c0d925",
+			Version: "2022.2.0",
+			Colos:   []string{"CGK", "SIN"},
+			RunAt:   &metav1.Time{Time: runAt},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("connectorStatus() = %v, want %v", got, want)
+	}
+}
+
+func TestConnectorsLastSeen(t *testing.T) {
+	runAt := time.Date(2022, 2, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		connectors []*cloudflare.TunnelConnector
+		want       *metav1.Time
+	}{
+		{
+			name: "no connectors",
+		},
+		{
+			name:       "no connections",
+			connectors: []*cloudflare.TunnelConnector{{RunAt: runAt}},
+		},
+		{
+			name: "latest opened connection",
+			connectors: []*cloudflare.TunnelConnector{
+				{RunAt: runAt, Connections: []cloudflare.TunnelConnection{{OpenedAt: runAt.Add(time.Minute)}, {OpenedAt: runAt.Add(time.Hour)}}},
+				{RunAt: runAt, Connections: []cloudflare.TunnelConnection{{OpenedAt: runAt.Add(time.Second)}}},
+			},
+			want: &metav1.Time{Time: runAt.Add(time.Hour)},
+		},
+		{
+			name:       "connection without opened time",
+			connectors: []*cloudflare.TunnelConnector{{RunAt: runAt, Connections: []cloudflare.TunnelConnection{{ColoName: "SIN"}}}},
+			want:       &metav1.Time{Time: runAt},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := connectorsLastSeen(tt.connectors); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("connectorsLastSeen() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTunnelReconciler_recordTunnelWarning(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(cloudflaredv1alpha1.AddToScheme(scheme))
+
+	ing := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", UID: "ingress-uid"},
+	}
+	isController := true
+	tests := []struct {
+		name   string
+		owners []metav1.OwnerReference
+		want   []string
+	}{
+		{
+			name: "not owned by ingress",
+			want: []string{
+				"Warning ReconcileFailed Unable to reconcile tunnel: boom",
+			},
+		},
+		{
+			name: "owned by ingress",
+			owners: []metav1.OwnerReference{
+				{APIVersion: "networking.k8s.io/v1", Kind: "Ingress", Name: ing.Name, UID: ing.UID, Controller: &isController},
+			},
+			want: []string{
+				"Warning ReconcileFailed Unable to reconcile tunnel: boom",
+				"Warning TunnelFailed Tunnel foo: Unable to reconcile tunnel: boom",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(len(tt.want) + 1)
+			r := &TunnelReconciler{
+				Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(ing.DeepCopy()).Build(),
+				Scheme:   scheme,
+				Recorder: recorder,
+			}
+			tunnel := &cloudflaredv1alpha1.Tunnel{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", OwnerReferences: tt.owners},
+			}
+
+			r.recordTunnelWarning(context.Background(), tunnel, ReasonReconcileFailed, "Unable to reconcile tunnel: %v", "boom")
+			close(recorder.Events)
+			var got []string
+			for event := range recorder.Events {
+				got = append(got, event)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("recordTunnelWarning() events = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTunnelReconciler_getCloudTunnel(t *testing.T) {
+	recordedID := uuid.New()
+	adoptedID := uuid.New()
+	tests := []struct {
+		name       string
+		tunnelID   string
+		byID       map[string]*cloudflare.Tunnel
+		byName     []*cloudflare.Tunnel
+		want       uuid.UUID
+		wantErr    bool
+		wantNotFnd bool
+	}{
+		{
+			name:     "recorded tunnel",
+			tunnelID: recordedID.String(),
+			byID:     map[string]*cloudflare.Tunnel{recordedID.String(): {ID: recordedID, Name: "k8s-foo"}},
+			want:     recordedID,
+		},
+		{
+			name:   "no recorded tunnel",
+			byName: []*cloudflare.Tunnel{{ID: adoptedID, Name: "k8s-foo"}},
+			want:   adoptedID,
+		},
+		{
+			name:     "recorded tunnel deleted",
+			tunnelID: recordedID.String(),
+			byID:     map[string]*cloudflare.Tunnel{recordedID.String(): {ID: recordedID, Name: "k8s-foo", DeletedAt: time.Now()}},
+			byName:   []*cloudflare.Tunnel{{ID: adoptedID, Name: "k8s-foo"}},
+			want:     adoptedID,
+		},
+		{
+			name:     "recorded tunnel not found",
+			tunnelID: recordedID.String(),
+			byName:   []*cloudflare.Tunnel{{ID: adoptedID, Name: "k8s-foo"}},
+			want:     adoptedID,
+		},
+		{
+			name:       "recorded tunnel gone without replacement",
+			tunnelID:   recordedID.String(),
+			wantErr:    true,
+			wantNotFnd: true,
+		},
+		{
+			name:       "recorded tunnel still listed by name",
+			tunnelID:   recordedID.String(),
+			byName:     []*cloudflare.Tunnel{{ID: recordedID, Name: "k8s-foo"}},
+			wantErr:    true,
+			wantNotFnd: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfclient := newTestCloudflareClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path := strings.TrimPrefix(r.URL.Path, "/client/v4/accounts/test-account/tunnels")
+				if path == "" {
+					if r.URL.Query().Get("name") != "k8s-foo" {
+						t.Errorf("unexpected tunnel name %q", r.URL.Query().Get("name"))
+					}
+
+					writeCloudflareResponse(w, append([]*cloudflare.Tunnel{}, tt.byName...))
+					return
+				}
+
+				if tunnel, ok := tt.byID[strings.TrimPrefix(path, "/")]; ok {
+					writeCloudflareResponse(w, tunnel)
+					return
+				}
+
+				writeCloudflareResponse(w, nil)
+			}))
+			r := &TunnelReconciler{}
+			tunnel := &cloudflaredv1alpha1.Tunnel{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+				Status:     cloudflaredv1alpha1.TunnelStatus{TunnelID: tt.tunnelID},
+			}
+
+			got, err := r.getCloudTunnel(context.Background(), cfclient, tunnel)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getCloudTunnel() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				if got := cloudflare.IsNotFound(err); got != tt.wantNotFnd {
+					t.Errorf("getCloudTunnel() IsNotFound = %v, want %v", got, tt.wantNotFnd)
+				}
+
+				return
+			}
+
+			if got.ID != tt.want {
+				t.Errorf("getCloudTunnel() = %v, want %v", got.ID, tt.want)
+			}
+		})
+	}
+}
+
+func TestTunnelReconciler_reconcileCredentialsSecret(t *testing.T) {
+	scheme := newTestScheme()
+	tunnel := &cloudflaredv1alpha1.Tunnel{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", UID: "tunnel-uid"},
+	}
+	credentials := &cloudflare.TunnelCredentials{AccountTag: "test-account", TunnelID: uuid.New(), TunnelSecret: []byte("secret"), TunnelName: "k8s-foo"}
+	previous := &cloudflare.TunnelCredentials{AccountTag: "test-account", TunnelID: uuid.New(), TunnelSecret: []byte("previous"), TunnelName: "k8s-foo"}
+	token := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf(`{"a":"test-account","s":"c2VjcmV0","t":"%s"}`, credentials.TunnelID)))
+	secretOf := func(credentials *cloudflare.TunnelCredentials) *corev1.Secret {
+		data, _ := json.Marshal(credentials)
+		return resources.NewTunnelResources(tunnel).Secret(map[string][]byte{"k8s-foo.json": data})
+	}
+	tests := []struct {
+		name         string
+		existing     []runtime.Object
+		wantRequests int
+		wantEvents   int
+	}{
+		{
+			name:         "no secret",
+			wantRequests: 1,
+			wantEvents:   1,
+		},
+		{
+			name:         "secret of previous tunnel",
+			existing:     []runtime.Object{secretOf(previous)},
+			wantRequests: 1,
+			wantEvents:   1,
+		},
+		{
+			name:     "secret of the tunnel",
+			existing: []runtime.Object{secretOf(credentials)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int
+			cfclient := newTestCloudflareClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if want := "/client/v4/accounts/test-account/cfd_tunnel/" + credentials.TunnelID.String() + "/token"; r.Method != http.MethodGet || r.URL.Path != want {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+				}
+
+				writeCloudflareResponse(w, token)
+			}))
+			recorder := record.NewFakeRecorder(10)
+			r := &TunnelReconciler{
+				Client:   fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(tt.existing...).Build(),
+				Scheme:   scheme,
+				Recorder: recorder,
+			}
+
+			if err := r.reconcileCredentialsSecret(context.Background(), cfclient, tunnel, credentials.TunnelID); err != nil {
+				t.Fatalf("reconcileCredentialsSecret() error = %v", err)
+			}
+
+			got := &corev1.Secret{}
+			if err := r.Client.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "foo-secret"}, got); err != nil {
+				t.Fatal(err)
+			}
+
+			if want := secretOf(credentials); !reflect.DeepEqual(got.Data, want.Data) {
+				t.Errorf("reconcileCredentialsSecret() data = %s, want %s", got.Data, want.Data)
+			}
+
+			if requests != tt.wantRequests {
+				t.Errorf("reconcileCredentialsSecret() requests = %v, want %v", requests, tt.wantRequests)
+			}
+
+			if len(recorder.Events) != tt.wantEvents {
+				t.Errorf("reconcileCredentialsSecret() events = %v, want %v", len(recorder.Events), tt.wantEvents)
+			}
+		})
+	}
+}
+
+func TestTunnelReconciler_removeNetworkRoutes(t *testing.T) {
+	tunnelID := uuid.New()
+	var deleted []string
+	cfclient := newTestCloudflareClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			if got := r.URL.Query(); got.Get("tunnel_id") != tunnelID.String() || got.Get("is_deleted") != "false" {
+				t.Errorf("unexpected query %v", got)
+			}
+
+			writeCloudflareResponse(w, []*cloudflare.NetworkRoute{
+				{ID: "route-a", Network: "10.0.0.0/8", TunnelID: tunnelID},
+				{ID: "route-b", Network: "192.168.0.0/16", TunnelID: tunnelID},
+			})
+		case http.MethodDelete:
+			deleted = append(deleted, strings.TrimPrefix(r.URL.Path, "/client/v4/accounts/test-account/teamnet/routes/"))
+			writeCloudflareResponse(w, struct{}{})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	r := &TunnelReconciler{Recorder: record.NewFakeRecorder(10)}
+	tunnel := &cloudflaredv1alpha1.Tunnel{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}}
+
+	if err := r.removeNetworkRoutes(context.Background(), cfclient, tunnel, tunnelID); err != nil {
+		t.Fatalf("removeNetworkRoutes() error = %v", err)
+	}
+
+	if want := []string{"route-a", "route-b"}; !reflect.DeepEqual(deleted, want) {
+		t.Errorf("removeNetworkRoutes() deleted = %v, want %v", deleted, want)
+	}
+}
+
+func TestTunnelReconciler_removeTunnelRoute(t *testing.T) {
+	tunnelID := uuid.New()
+	records := []*cloudflare.DNSRecord{
+		{ID: "tunnel-record", Type: cloudflare.DNSRecordTypeCNAME, Name: "foo.example.com", Content: cloudflare.TunnelHostname(tunnelID)},
+		{ID: "foreign-record", Type: cloudflare.DNSRecordTypeCNAME, Name: "foo.example.com", Content: cloudflare.TunnelHostname(uuid.New())},
+		{ID: "other-record", Type: cloudflare.DNSRecordTypeCNAME, Name: "bar.example.com", Content: cloudflare.TunnelHostname(tunnelID)},
+	}
+	var deleted []string
+	cfclient := newTestCloudflareClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			query := r.URL.Query()
+			matches := func(key, value string) bool {
+				return query.Get(key) == "" || query.Get(key) == value
+			}
+			var matched []*cloudflare.DNSRecord
+			for _, record := range records {
+				if matches("type", record.Type) && matches("name", record.Name) && matches("content", record.Content) {
+					matched = append(matched, record)
+				}
+			}
+
+			writeCloudflareResponse(w, matched)
+		case http.MethodDelete:
+			deleted = append(deleted, strings.TrimPrefix(r.URL.Path, "/client/v4/zones/test-zone/dns_records/"))
+			writeCloudflareResponse(w, struct{}{})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	recorder := record.NewFakeRecorder(10)
+	r := &TunnelReconciler{Recorder: recorder}
+	tunnel := &cloudflaredv1alpha1.Tunnel{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}}
+
+	if err := r.removeTunnelRoute(context.Background(), cfclient, tunnel, "test-zone", tunnelID, "foo.example.com"); err != nil {
+		t.Fatalf("removeTunnelRoute() error = %v", err)
+	}
+
+	if want := []string{"tunnel-record"}; !reflect.DeepEqual(deleted, want) {
+		t.Errorf("removeTunnelRoute() deleted = %v, want %v", deleted, want)
+	}
+
+	if len(recorder.Events) != 1 {
+		t.Errorf("removeTunnelRoute() events = %v, want %v", len(recorder.Events), 1)
+	}
+}
+
+func TestTunnelReconciler_reconcileDelete(t *testing.T) {
+	scheme := newTestScheme()
+	tunnelID := uuid.New()
+	newTunnel := func() *cloudflaredv1alpha1.Tunnel {
+		return &cloudflaredv1alpha1.Tunnel{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "foo",
+				Namespace:  "default",
+				UID:        "tunnel-uid",
+				Finalizers: []string{cloudflaredv1alpha1.TunnelFinalizer},
+			},
+			Status: cloudflaredv1alpha1.TunnelStatus{TunnelID: tunnelID.String()},
+		}
+	}
+	deploymentOf := func(replicas int32) *appsv1.Deployment {
+		dep := resources.NewTunnelResources(newTunnel()).Deployment()
+		dep.Spec.Replicas = pointer.Int32Ptr(replicas)
+		return dep
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo-abc",
+			Namespace: "default",
+			Labels:    resources.NewTunnelResources(newTunnel()).CommonLabels(),
+		},
+	}
+	tunnelPath := "/client/v4/accounts/test-account/tunnels/" + tunnelID.String()
+	tests := []struct {
+		name              string
+		existing          []client.Object
+		conditions        []metav1.Condition
+		want              ctrl.Result
+		wantRequests      []string
+		wantFinalizer     bool
+		wantWaitingEvents int
+	}{
+		{
+			name:              "pods remaining",
+			existing:          []client.Object{deploymentOf(1), pod},
+			want:              ctrl.Result{RequeueAfter: connectorTerminationPollPeriod},
+			wantFinalizer:     true,
+			wantWaitingEvents: 1,
+		},
+		{
+			name:     "pods still remaining",
+			existing: []client.Object{deploymentOf(0), pod},
+			conditions: []metav1.Condition{{
+				Type:   cloudflaredv1alpha1.ConnectorsAvailableCondition,
+				Status: metav1.ConditionFalse,
+				Reason: cloudflaredv1alpha1.TerminatingConnectorsReason,
+			}},
+			want:          ctrl.Result{RequeueAfter: connectorTerminationPollPeriod},
+			wantFinalizer: true,
+		},
+		{
+			name:     "pods terminated",
+			existing: []client.Object{deploymentOf(0)},
+			wantRequests: []string{
+				"GET " + tunnelPath,
+				"DELETE " + tunnelPath + "/connections",
+				"GET /client/v4/accounts/test-account/teamnet/routes",
+				"DELETE " + tunnelPath,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []string
+			cfclient := newTestCloudflareClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r.Method+" "+r.URL.Path)
+				switch {
+				case r.Method == http.MethodGet && r.URL.Path == tunnelPath:
+					writeCloudflareResponse(w, &cloudflare.Tunnel{ID: tunnelID, Name: "k8s-foo"})
+				case r.Method == http.MethodGet:
+					writeCloudflareResponse(w, []*cloudflare.NetworkRoute{})
+				default:
+					writeCloudflareResponse(w, struct{}{})
+				}
+			}))
+			recorder := record.NewFakeRecorder(10)
+			r := &TunnelReconciler{
+				Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(tt.existing...).Build(),
+				Scheme:   scheme,
+				Recorder: recorder,
+			}
+			tunnel := newTunnel()
+			tunnel.Status.Conditions = tt.conditions
+
+			got, err := r.reconcileDelete(context.Background(), cfclient, tunnel)
+			if err != nil {
+				t.Fatalf("reconcileDelete() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("reconcileDelete() = %v, want %v", got, tt.want)
+			}
+
+			if !reflect.DeepEqual(requests, tt.wantRequests) {
+				t.Errorf("reconcileDelete() requests = %v, want %v", requests, tt.wantRequests)
+			}
+
+			if got := controllerutil.ContainsFinalizer(tunnel, cloudflaredv1alpha1.TunnelFinalizer); got != tt.wantFinalizer {
+				t.Errorf("reconcileDelete() finalizer = %v, want %v", got, tt.wantFinalizer)
+			}
+
+			close(recorder.Events)
+			var waitingEvents int
+			for event := range recorder.Events {
+				if strings.HasPrefix(event, corev1.EventTypeNormal+" "+ReasonWaitingForConnectors) {
+					waitingEvents++
+				}
+			}
+
+			if waitingEvents != tt.wantWaitingEvents {
+				t.Errorf("reconcileDelete() %s events = %v, want %v", ReasonWaitingForConnectors, waitingEvents, tt.wantWaitingEvents)
+			}
+
+			dep := &appsv1.Deployment{}
+			if err := r.Client.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "foo"}, dep); err != nil {
+				t.Fatal(err)
+			}
+
+			if *dep.Spec.Replicas != 0 {
+				t.Errorf("reconcileDelete() replicas = %v, want 0", *dep.Spec.Replicas)
+			}
+		})
+	}
+}
+
+func TestTunnelReconciler_reconcileRoutes(t *testing.T) {
+	tunnelID := uuid.New()
+	routeZone := func(hostname, zone, zoneID string) cloudflaredv1alpha1.TunnelRouteZone {
+		return cloudflaredv1alpha1.TunnelRouteZone{Hostname: hostname, Zone: zone, ZoneID: zoneID}
+	}
+	tests := []struct {
+		name         string
+		hostnames    []string
+		status       cloudflaredv1alpha1.TunnelStatus
+		wantRequests []string
+		wantStatus   cloudflaredv1alpha1.TunnelStatus
+	}{
+		{
+			name:      "new hostname",
+			hostnames: []string{"foo.example.com"},
+			wantRequests: []string{
+				"PUT /client/v4/zones/test-zone/tunnels/" + tunnelID.String() + "/routes",
+			},
+			wantStatus: cloudflaredv1alpha1.TunnelStatus{
+				Routes: []string{"foo.example.com"},
+				Zones:  []cloudflaredv1alpha1.TunnelRouteZone{routeZone("foo.example.com", "example.com", "test-zone")},
+			},
+		},
+		{
+			name:      "hostname removed from spec",
+			hostnames: []string{"foo.example.com"},
+			status: cloudflaredv1alpha1.TunnelStatus{
+				Routes: []string{"bar.example.com", "foo.example.com"},
+				Zones: []cloudflaredv1alpha1.TunnelRouteZone{
+					routeZone("bar.example.com", "example.com", "test-zone"),
+					routeZone("foo.example.com", "example.com", "test-zone"),
+				},
+			},
+			wantRequests: []string{
+				"GET /client/v4/zones/test-zone/dns_records",
+				"DELETE /client/v4/zones/test-zone/dns_records/bar-record",
+			},
+			wantStatus: cloudflaredv1alpha1.TunnelStatus{
+				Routes: []string{"foo.example.com"},
+				Zones:  []cloudflaredv1alpha1.TunnelRouteZone{routeZone("foo.example.com", "example.com", "test-zone")},
+			},
+		},
+		{
+			name:      "hostname without reachable zone",
+			hostnames: []string{"foo.example.com", "foo.example.org"},
+			status: cloudflaredv1alpha1.TunnelStatus{
+				Routes: []string{"foo.example.com", "foo.example.org"},
+				Zones: []cloudflaredv1alpha1.TunnelRouteZone{
+					routeZone("foo.example.com", "example.com", "test-zone"),
+					routeZone("foo.example.org", "example.org", "other-zone"),
+				},
+			},
+			wantStatus: cloudflaredv1alpha1.TunnelStatus{
+				Routes: []string{"foo.example.com", "foo.example.org"},
+				Zones: []cloudflaredv1alpha1.TunnelRouteZone{
+					routeZone("foo.example.com", "example.com", "test-zone"),
+					routeZone("foo.example.org", "example.org", "other-zone"),
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []string
+			cfclient := newTestCloudflareClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.URL.Path == "/client/v4/zones":
+					writeCloudflareResponse(w, []*cloudflare.Zone{{ID: "test-zone", Name: "example.com"}})
+					return
+				case r.Method == http.MethodGet:
+					writeCloudflareResponse(w, []*cloudflare.DNSRecord{{ID: "bar-record", Name: r.URL.Query().Get("name")}})
+				default:
+					writeCloudflareResponse(w, struct{}{})
+				}
+
+				requests = append(requests, r.Method+" "+r.URL.Path)
+			}))
+			r := &TunnelReconciler{Recorder: record.NewFakeRecorder(10)}
+			tunnel := &cloudflaredv1alpha1.Tunnel{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+				Status:     tt.status,
+			}
+			for _, hostname := range tt.hostnames {
+				tunnel.Spec.IngressRules = append(tunnel.Spec.IngressRules, cloudflaredv1alpha1.TunnelIngressRule{Hostname: hostname, Service: "http://foo:8000"})
+			}
+
+			if err := r.reconcileRoutes(context.Background(), cfclient, tunnel, tunnelID); err != nil {
+				t.Fatalf("reconcileRoutes() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(requests, tt.wantRequests) {
+				t.Errorf("reconcileRoutes() requests = %v, want %v", requests, tt.wantRequests)
+			}
+
+			if !reflect.DeepEqual(tunnel.Status, tt.wantStatus) {
+				t.Errorf("reconcileRoutes() status = %+v, want %+v", tunnel.Status, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,339 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/google/uuid"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	cloudflaredv1alpha1 "github.com/prksu/cloudflared-controller/api/v1alpha1"
+	"github.com/prksu/cloudflared-controller/cloudflare"
+	"github.com/prksu/cloudflared-controller/util"
+	"github.com/prksu/cloudflared-controller/util/patch"
+)
+
+const (
+	TunnelNetworkRouteControllerName = "cloudflared.cloudflare.com/tunnelnetworkroute-controller"
+
+	// tunnelPendingPollPeriod is how often the referenced Tunnel is checked while waiting it to be created.
+	tunnelPendingPollPeriod = 10 * time.Second
+)
+
+// TunnelNetworkRouteReconciler reconciles a TunnelNetworkRoute object
+type TunnelNetworkRouteReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	// ClientCache shares the cloudflare clients between reconciles.
+	ClientCache *util.ClientCache
+}
+
+// +kubebuilder:rbac:groups=cloudflared.cloudflare.com,resources=tunnelnetworkroutes,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=cloudflared.cloudflare.com,resources=tunnelnetworkroutes/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=cloudflared.cloudflare.com,resources=tunnelnetworkroutes/finalizers,verbs=update
+// +kubebuilder:rbac:groups=cloudflared.cloudflare.com,resources=tunnels,verbs=get;list;watch
+// +kubebuilder:rbac:groups=cloudflared.cloudflare.com,resources=virtualnetworks,verbs=get;list;watch
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *TunnelNetworkRouteReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&cloudflaredv1alpha1.TunnelNetworkRoute{}).
+		Complete(r)
+}
+
+func (r *TunnelNetworkRouteReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
+	log := log.FromContext(ctx)
+	route := &cloudflaredv1alpha1.TunnelNetworkRoute{}
+	if err := r.Client.Get(ctx, req.NamespacedName, route); err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Info("TunnelNetworkRoute resource not found or already deleted")
+			return ctrl.Result{}, nil
+		}
+
+		log.Error(err, "unable to fetch TunnelNetworkRoute resource")
+		return ctrl.Result{}, err
+	}
+
+	patcher, err := patch.NewPatcher(route, r.Client)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	defer func() {
+		if err := patcher.Patch(ctx, route); err != nil {
+			log.Error(err, "unable patch TunnelNetworkRoute resource")
+			reterr = err
+		}
+	}()
+
+	tunnel := &cloudflaredv1alpha1.Tunnel{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: req.Namespace, Name: route.Spec.TunnelRef.Name}, tunnel); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+
+		if !route.DeletionTimestamp.IsZero() {
+			// Without the Tunnel there are no credentials to reach cloudflare anymore. The Tunnel
+			// removes the network routes of its cloudflare tunnel when it is deleted.
+			log.Info("Tunnel not found, leaving the network routes to be removed along with the tunnel")
+			controllerutil.RemoveFinalizer(route, cloudflaredv1alpha1.TunnelNetworkRouteFinalizer)
+			return ctrl.Result{}, nil
+		}
+
+		log.Info("Waiting for Tunnel to be created", "tunnel", route.Spec.TunnelRef.Name)
+		return ctrl.Result{RequeueAfter: tunnelPendingPollPeriod}, nil
+	}
+
+	cfclient, err := r.ClientCache.Get(ctx, r.Client, req.Namespace, tunnel.Spec.TunnelConfigurationSpec)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !route.DeletionTimestamp.IsZero() {
+		return r.reconcileDelete(ctx, cfclient, route)
+	}
+
+	return r.reconcile(ctx, cfclient, route, tunnel)
+}
+
+func (r *TunnelNetworkRouteReconciler) reconcile(ctx context.Context, cfclient cloudflare.Client, route *cloudflaredv1alpha1.TunnelNetworkRoute, tunnel *cloudflaredv1alpha1.Tunnel) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	controllerutil.AddFinalizer(route, cloudflaredv1alpha1.TunnelNetworkRouteFinalizer)
+	log.Info("Reconciling")
+
+	tunnelID, err := uuid.Parse(tunnel.Status.TunnelID)
+	if err != nil {
+		log.Info("Waiting for cloudflare tunnel to be created", "tunnel", tunnel.Name)
+		return ctrl.Result{RequeueAfter: tunnelPendingPollPeriod}, nil
+	}
+
+	vnetID, err := r.virtualNetworkID(ctx, route, tunnel)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	vnetName := virtualNetworkName(route, tunnel)
+	if vnetName != "" && vnetID == "" {
+		log.Info("Waiting for VirtualNetwork to be created", "virtual-network", vnetName)
+		return ctrl.Result{RequeueAfter: tunnelPendingPollPeriod}, nil
+	}
+
+	// The routes have to be moved when the TunnelRef is changed, the cloudflare tunnel is recreated
+	// or another virtual network is selected.
+	tunnelChanged := route.Status.TunnelID != "" && route.Status.TunnelID != tunnelID.String()
+	vnetChanged := len(route.Status.Routes) != 0 && route.Status.VirtualNetworkID != vnetID
+	if tunnelChanged || vnetChanged {
+		log.Info("Removing previous network routes", "tunnel-id", route.Status.TunnelID, "vnet-id", route.Status.VirtualNetworkID)
+		if err := r.removeNetworkRoutes(ctx, cfclient, route, route.Status.Routes); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	route.Status.TunnelID = tunnelID.String()
+	route.Status.VirtualNetworkID = vnetID
+
+	desiredNetworks := make(map[string]bool, len(route.Spec.CIDRs))
+	for _, cidr := range route.Spec.CIDRs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			r.Recorder.Eventf(route, corev1.EventTypeWarning, ReasonInvalidNetworkRoute, "Invalid CIDR %q: %v", cidr, err)
+			return ctrl.Result{}, err
+		}
+
+		desiredNetworks[network.String()] = true
+	}
+
+	var staleRoutes []cloudflaredv1alpha1.TunnelNetworkRouteEntry
+	actualNetworks := make(map[string]bool, len(route.Status.Routes))
+	for _, entry := range route.Status.Routes {
+		if desiredNetworks[entry.Network] {
+			actualNetworks[entry.Network] = true
+		} else {
+			staleRoutes = append(staleRoutes, entry)
+		}
+	}
+
+	if err := r.removeNetworkRoutes(ctx, cfclient, route, staleRoutes); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	for _, cidr := range route.Spec.CIDRs {
+		_, network, _ := net.ParseCIDR(cidr)
+		if actualNetworks[network.String()] {
+			continue
+		}
+
+		log.Info("Creating network route", "network", network.String())
+		cfroute, err := r.createNetworkRoute(ctx, cfclient, &cloudflare.NetworkRoute{
+			Network:          network.String(),
+			TunnelID:         tunnelID,
+			Comment:          route.Spec.Comment,
+			VirtualNetworkID: vnetID,
+		})
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		actualNetworks[network.String()] = true
+		route.Status.Routes = append(route.Status.Routes, cloudflaredv1alpha1.TunnelNetworkRouteEntry{
+			ID:      cfroute.ID,
+			Network: network.String(),
+		})
+		r.Recorder.Eventf(route, corev1.EventTypeNormal, ReasonNetworkRouteCreated, "Routed %s through cloudflare tunnel %s", network, tunnelID)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func (r *TunnelNetworkRouteReconciler) reconcileDelete(ctx context.Context, cfclient cloudflare.Client, route *cloudflaredv1alpha1.TunnelNetworkRoute) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	log.Info("Reconciling to deleting")
+
+	if err := r.removeNetworkRoutes(ctx, cfclient, route, route.Status.Routes); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	controllerutil.RemoveFinalizer(route, cloudflaredv1alpha1.TunnelNetworkRouteFinalizer)
+	return ctrl.Result{}, nil
+}
+
+// virtualNetworkID returns the ID of VirtualNetwork selected by the TunnelNetworkRoute or its Tunnel.
+// An empty ID is returned when none is selected, or the VirtualNetwork is not created yet.
+func (r *TunnelNetworkRouteReconciler) virtualNetworkID(ctx context.Context, route *cloudflaredv1alpha1.TunnelNetworkRoute, tunnel *cloudflaredv1alpha1.Tunnel) (string, error) {
+	name := virtualNetworkName(route, tunnel)
+	if name == "" {
+		return "", nil
+	}
+
+	vnet := &cloudflaredv1alpha1.VirtualNetwork{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Name: name}, vnet); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	if !vnet.DeletionTimestamp.IsZero() {
+		return "", nil
+	}
+
+	return vnet.Status.ID, nil
+}
+
+// virtualNetworkName returns the name of VirtualNetwork selected by the TunnelNetworkRoute or its Tunnel.
+func virtualNetworkName(route *cloudflaredv1alpha1.TunnelNetworkRoute, tunnel *cloudflaredv1alpha1.Tunnel) string {
+	if route.Spec.VirtualNetwork != "" {
+		return route.Spec.VirtualNetwork
+	}
+
+	return tunnel.Spec.VirtualNetwork
+}
+
+// createNetworkRoute creates the network route, or adopts the existing one
+// when the network is already routed through the same tunnel.
+func (r *TunnelNetworkRouteReconciler) createNetworkRoute(ctx context.Context, cfclient cloudflare.Client, route *cloudflare.NetworkRoute) (*cloudflare.NetworkRoute, error) {
+	created, err := cfclient.NetworkRoutes().Create(ctx, route)
+	if err == nil {
+		return created, nil
+	}
+
+	if !cloudflare.IsAlreadyExists(err) {
+		RouteFailuresTotal.WithLabelValues(RouteTypeNetwork, RouteOperationCreate).Inc()
+		return nil, err
+	}
+
+	existing, listErr := r.findNetworkRoute(ctx, cfclient, route)
+	if listErr != nil {
+		RouteFailuresTotal.WithLabelValues(RouteTypeNetwork, RouteOperationCreate).Inc()
+		return nil, listErr
+	}
+
+	if existing == nil {
+		// The network is routed through another tunnel.
+		RouteFailuresTotal.WithLabelValues(RouteTypeNetwork, RouteOperationCreate).Inc()
+		return nil, err
+	}
+
+	return existing, nil
+}
+
+// findNetworkRoute returns the existing route of the network through the same tunnel
+// and in the same virtual network, or nil if there is none.
+func (r *TunnelNetworkRouteReconciler) findNetworkRoute(ctx context.Context, cfclient cloudflare.Client, route *cloudflare.NetworkRoute) (*cloudflare.NetworkRoute, error) {
+	cfroutes, err := cfclient.NetworkRoutes().List(ctx, &cloudflare.NetworkRouteListOptions{
+		TunnelID:         route.TunnelID.String(),
+		VirtualNetworkID: route.VirtualNetworkID,
+		NetworkSubset:    route.Network,
+		NetworkSuperset:  route.Network,
+		IsDeleted:        pointer.BoolPtr(false),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cfroute := range cfroutes {
+		if cfroute.Network == route.Network && cfroute.TunnelID == route.TunnelID {
+			return cfroute, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// removeNetworkRoutes deletes given routes and drops them from the TunnelNetworkRoute status.
+func (r *TunnelNetworkRouteReconciler) removeNetworkRoutes(ctx context.Context, cfclient cloudflare.Client, route *cloudflaredv1alpha1.TunnelNetworkRoute, entries []cloudflaredv1alpha1.TunnelNetworkRouteEntry) error {
+	log := log.FromContext(ctx)
+	removed := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		log.Info("Deleting network route", "network", entry.Network, "route-id", entry.ID)
+		if err := cfclient.NetworkRoutes().Delete(ctx, entry.ID); err != nil && !cloudflare.IsNotFound(err) {
+			RouteFailuresTotal.WithLabelValues(RouteTypeNetwork, RouteOperationDelete).Inc()
+			return err
+		}
+
+		removed[entry.ID] = true
+		r.Recorder.Eventf(route, corev1.EventTypeNormal, ReasonNetworkRouteDeleted, "Removed route of %s", entry.Network)
+	}
+
+	var remaining []cloudflaredv1alpha1.TunnelNetworkRouteEntry
+	for _, entry := range route.Status.Routes {
+		if !removed[entry.ID] {
+			remaining = append(remaining, entry)
+		}
+	}
+
+	route.Status.Routes = remaining
+	if len(remaining) == 0 {
+		route.Status.TunnelID = ""
+		route.Status.VirtualNetworkID = ""
+	}
+
+	return nil
+}
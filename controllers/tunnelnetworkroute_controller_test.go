@@ -0,0 +1,219 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	cloudflaredv1alpha1 "github.com/prksu/cloudflared-controller/api/v1alpha1"
+	"github.com/prksu/cloudflared-controller/cloudflare"
+)
+
+func TestTunnelNetworkRouteReconciler_Reconcile(t *testing.T) {
+	tunnelID := uuid.New()
+	previousTunnelID := uuid.New()
+	deletedAt := metav1.NewTime(time.Now())
+	tunnel := &cloudflaredv1alpha1.Tunnel{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: cloudflaredv1alpha1.TunnelSpec{
+			TunnelConfigurationSpec: newTestTunnelConfiguration("default").Spec,
+		},
+		Status: cloudflaredv1alpha1.TunnelStatus{TunnelID: tunnelID.String()},
+	}
+	newRoute := func(deleting bool, status cloudflaredv1alpha1.TunnelNetworkRouteStatus) *cloudflaredv1alpha1.TunnelNetworkRoute {
+		route := &cloudflaredv1alpha1.TunnelNetworkRoute{
+			ObjectMeta: metav1.ObjectMeta{Name: "networks", Namespace: "default"},
+			Spec: cloudflaredv1alpha1.TunnelNetworkRouteSpec{
+				TunnelRef: corev1.LocalObjectReference{Name: "foo"},
+				CIDRs:     []string{"10.0.0.0/8", "192.168.1.5/24"},
+			},
+			Status: status,
+		}
+		if deleting {
+			route.DeletionTimestamp = &deletedAt
+			route.Finalizers = []string{cloudflaredv1alpha1.TunnelNetworkRouteFinalizer}
+		}
+
+		return route
+	}
+	tests := []struct {
+		name          string
+		route         *cloudflaredv1alpha1.TunnelNetworkRoute
+		tunnel        *cloudflaredv1alpha1.Tunnel
+		wantRequests  []string
+		wantResult    ctrl.Result
+		wantRoutes    []cloudflaredv1alpha1.TunnelNetworkRouteEntry
+		wantFinalizer bool
+	}{
+		{
+			name:  "cloudflare tunnel not created yet",
+			route: newRoute(false, cloudflaredv1alpha1.TunnelNetworkRouteStatus{}),
+			tunnel: &cloudflaredv1alpha1.Tunnel{
+				ObjectMeta: tunnel.ObjectMeta,
+				Spec:       tunnel.Spec,
+			},
+			wantResult:    ctrl.Result{RequeueAfter: tunnelPendingPollPeriod},
+			wantFinalizer: true,
+		},
+		{
+			name:         "create",
+			route:        newRoute(false, cloudflaredv1alpha1.TunnelNetworkRouteStatus{}),
+			tunnel:       tunnel,
+			wantRequests: []string{"POST 10.0.0.0/8", "POST 192.168.1.0/24"},
+			wantRoutes: []cloudflaredv1alpha1.TunnelNetworkRouteEntry{
+				{ID: "route-10.0.0.0_8", Network: "10.0.0.0/8"},
+				{ID: "route-192.168.1.0_24", Network: "192.168.1.0/24"},
+			},
+			wantFinalizer: true,
+		},
+		{
+			name: "remove stale network",
+			route: newRoute(false, cloudflaredv1alpha1.TunnelNetworkRouteStatus{
+				TunnelID: tunnelID.String(),
+				Routes: []cloudflaredv1alpha1.TunnelNetworkRouteEntry{
+					{ID: "route-10.0.0.0_8", Network: "10.0.0.0/8"},
+					{ID: "route-172.16.0.0_12", Network: "172.16.0.0/12"},
+				},
+			}),
+			tunnel:       tunnel,
+			wantRequests: []string{"DELETE route-172.16.0.0_12", "POST 192.168.1.0/24"},
+			wantRoutes: []cloudflaredv1alpha1.TunnelNetworkRouteEntry{
+				{ID: "route-10.0.0.0_8", Network: "10.0.0.0/8"},
+				{ID: "route-192.168.1.0_24", Network: "192.168.1.0/24"},
+			},
+			wantFinalizer: true,
+		},
+		{
+			name: "move to recreated tunnel",
+			route: newRoute(false, cloudflaredv1alpha1.TunnelNetworkRouteStatus{
+				TunnelID: previousTunnelID.String(),
+				Routes: []cloudflaredv1alpha1.TunnelNetworkRouteEntry{
+					{ID: "route-10.0.0.0_8", Network: "10.0.0.0/8"},
+				},
+			}),
+			tunnel:       tunnel,
+			wantRequests: []string{"DELETE route-10.0.0.0_8", "POST 10.0.0.0/8", "POST 192.168.1.0/24"},
+			wantRoutes: []cloudflaredv1alpha1.TunnelNetworkRouteEntry{
+				{ID: "route-10.0.0.0_8", Network: "10.0.0.0/8"},
+				{ID: "route-192.168.1.0_24", Network: "192.168.1.0/24"},
+			},
+			wantFinalizer: true,
+		},
+		{
+			name: "delete",
+			route: newRoute(true, cloudflaredv1alpha1.TunnelNetworkRouteStatus{
+				TunnelID: tunnelID.String(),
+				Routes: []cloudflaredv1alpha1.TunnelNetworkRouteEntry{
+					{ID: "route-10.0.0.0_8", Network: "10.0.0.0/8"},
+				},
+			}),
+			tunnel:       tunnel,
+			wantRequests: []string{"DELETE route-10.0.0.0_8"},
+		},
+		{
+			name: "delete without tunnel",
+			route: newRoute(true, cloudflaredv1alpha1.TunnelNetworkRouteStatus{
+				TunnelID: tunnelID.String(),
+				Routes: []cloudflaredv1alpha1.TunnelNetworkRouteEntry{
+					{ID: "route-10.0.0.0_8", Network: "10.0.0.0/8"},
+				},
+			}),
+			wantRoutes: []cloudflaredv1alpha1.TunnelNetworkRouteEntry{
+				{ID: "route-10.0.0.0_8", Network: "10.0.0.0/8"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []string
+			cache := newTestClientCache(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.Method {
+				case http.MethodPost:
+					cfroute := &cloudflare.NetworkRoute{}
+					if err := json.NewDecoder(r.Body).Decode(cfroute); err != nil {
+						t.Fatal(err)
+					}
+
+					if cfroute.TunnelID != tunnelID {
+						t.Errorf("route tunnel = %v, want %v", cfroute.TunnelID, tunnelID)
+					}
+
+					requests = append(requests, "POST "+cfroute.Network)
+					cfroute.ID = "route-" + strings.Replace(cfroute.Network, "/", "_", 1)
+					writeCloudflareResponse(w, cfroute)
+				case http.MethodDelete:
+					requests = append(requests, "DELETE "+path.Base(r.URL.Path))
+					writeCloudflareResponse(w, struct{}{})
+				default:
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+				}
+			}))
+			objs := []client.Object{tt.route}
+			if tt.tunnel != nil {
+				objs = append(objs, tt.tunnel)
+			}
+
+			r := &TunnelNetworkRouteReconciler{
+				Client:      fake.NewClientBuilder().WithScheme(newTestScheme()).WithObjects(objs...).Build(),
+				Recorder:    record.NewFakeRecorder(10),
+				ClientCache: cache,
+			}
+
+			got, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(tt.route)})
+			if err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+
+			if got != tt.wantResult {
+				t.Errorf("Reconcile() = %v, want %v", got, tt.wantResult)
+			}
+
+			if !reflect.DeepEqual(requests, tt.wantRequests) {
+				t.Errorf("Reconcile() cloudflare requests = %v, want %v", requests, tt.wantRequests)
+			}
+
+			route := &cloudflaredv1alpha1.TunnelNetworkRoute{}
+			if err := r.Client.Get(context.Background(), client.ObjectKeyFromObject(tt.route), route); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(route.Status.Routes, tt.wantRoutes) {
+				t.Errorf("Reconcile() routes = %v, want %v", route.Status.Routes, tt.wantRoutes)
+			}
+
+			if got := controllerutil.ContainsFinalizer(route, cloudflaredv1alpha1.TunnelNetworkRouteFinalizer); got != tt.wantFinalizer {
+				t.Errorf("Reconcile() finalizer = %v, want %v", got, tt.wantFinalizer)
+			}
+		})
+	}
+}
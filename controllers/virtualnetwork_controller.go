@@ -0,0 +1,361 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	cloudflaredv1alpha1 "github.com/prksu/cloudflared-controller/api/v1alpha1"
+	"github.com/prksu/cloudflared-controller/cloudflare"
+	"github.com/prksu/cloudflared-controller/util"
+	"github.com/prksu/cloudflared-controller/util/patch"
+)
+
+const (
+	VirtualNetworkControllerName = "cloudflared.cloudflare.com/virtualnetwork-controller"
+
+	// virtualNetworkResyncPeriod is how often the default flag of VirtualNetwork is observed,
+	// since making another virtual network the default unsets it outside of this VirtualNetwork.
+	virtualNetworkResyncPeriod = 5 * time.Minute
+	// virtualNetworkInUsePollPeriod is how often the references are checked while the deletion is blocked.
+	virtualNetworkInUsePollPeriod = 10 * time.Second
+)
+
+// VirtualNetworkReconciler reconciles a VirtualNetwork object
+type VirtualNetworkReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	// ClientCache shares the cloudflare clients between reconciles.
+	ClientCache *util.ClientCache
+	// ClusterResourceNamespace is the only namespace the TunnelConfiguration of VirtualNetwork is read from.
+	ClusterResourceNamespace string
+}
+
+// +kubebuilder:rbac:groups=cloudflared.cloudflare.com,resources=virtualnetworks,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=cloudflared.cloudflare.com,resources=virtualnetworks/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=cloudflared.cloudflare.com,resources=virtualnetworks/finalizers,verbs=update
+// +kubebuilder:rbac:groups=cloudflared.cloudflare.com,resources=tunnelconfigurations,verbs=get;list;watch
+// +kubebuilder:rbac:groups=cloudflared.cloudflare.com,resources=tunnelnetworkroutes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=cloudflared.cloudflare.com,resources=tunnels,verbs=get;list;watch
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *VirtualNetworkReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&cloudflaredv1alpha1.VirtualNetwork{}).
+		Complete(r)
+}
+
+func (r *VirtualNetworkReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
+	log := log.FromContext(ctx)
+	vnet := &cloudflaredv1alpha1.VirtualNetwork{}
+	if err := r.Client.Get(ctx, req.NamespacedName, vnet); err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Info("VirtualNetwork resource not found or already deleted")
+			return ctrl.Result{}, nil
+		}
+
+		log.Error(err, "unable to fetch VirtualNetwork resource")
+		return ctrl.Result{}, err
+	}
+
+	patcher, err := patch.NewPatcher(vnet, r.Client)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	defer func() {
+		if err := patcher.Patch(ctx, vnet); err != nil {
+			log.Error(err, "unable patch VirtualNetwork resource")
+			reterr = err
+		}
+	}()
+
+	ref := vnet.Spec.ConfigurationRef
+	if ref.Namespace != r.ClusterResourceNamespace {
+		log.Info("TunnelConfiguration is not in the cluster resource namespace, ignoring", "namespace", ref.Namespace)
+		r.Recorder.Eventf(vnet, corev1.EventTypeWarning, ReasonInvalidConfigurationRef,
+			"TunnelConfiguration must be in the %s namespace, got %s", r.ClusterResourceNamespace, ref.Namespace)
+		if !vnet.DeletionTimestamp.IsZero() {
+			controllerutil.RemoveFinalizer(vnet, cloudflaredv1alpha1.VirtualNetworkFinalizer)
+		}
+
+		return ctrl.Result{}, nil
+	}
+
+	tunnelConfig := &cloudflaredv1alpha1.TunnelConfiguration{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}, tunnelConfig); err != nil {
+		if apierrors.IsNotFound(err) && !vnet.DeletionTimestamp.IsZero() {
+			log.Info("TunnelConfiguration not found, leaving the virtual network as is")
+			controllerutil.RemoveFinalizer(vnet, cloudflaredv1alpha1.VirtualNetworkFinalizer)
+			return ctrl.Result{}, nil
+		}
+
+		return ctrl.Result{}, err
+	}
+
+	cfclient, err := r.ClientCache.Get(ctx, r.Client, ref.Namespace, tunnelConfig.Spec)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !vnet.DeletionTimestamp.IsZero() {
+		return r.reconcileDelete(ctx, cfclient, vnet)
+	}
+
+	return r.reconcile(ctx, cfclient, vnet)
+}
+
+func (r *VirtualNetworkReconciler) reconcile(ctx context.Context, cfclient cloudflare.Client, vnet *cloudflaredv1alpha1.VirtualNetwork) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	controllerutil.AddFinalizer(vnet, cloudflaredv1alpha1.VirtualNetworkFinalizer)
+	log.Info("Reconciling")
+
+	log.Info("Ensuring cloudflare virtual network")
+	cfvnet, err := cfclient.VirtualNetworks().GetByName(ctx, vnet.Name)
+	switch {
+	case cloudflare.IsNotFound(err):
+		log.Info("Creating new cloudflare virtual network")
+		cfvnet, err = cfclient.VirtualNetworks().Create(ctx, &cloudflare.VirtualNetwork{
+			Name:             vnet.Name,
+			Comment:          vnet.Spec.Comment,
+			IsDefaultNetwork: vnet.Spec.Default,
+		})
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		vnet.Status.Created = true
+		r.Recorder.Eventf(vnet, corev1.EventTypeNormal, ReasonVirtualNetworkCreated, "Created cloudflare virtual network %s", cfvnet.ID)
+	case err != nil:
+		return ctrl.Result{}, err
+	}
+
+	// Two VirtualNetworks desiring the default would take it from each other on every resync,
+	// so only the oldest of them is made the default.
+	wantDefault := vnet.Spec.Default
+	if wantDefault {
+		conflict, err := r.defaultConflict(ctx, cfclient, vnet)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if conflict != "" {
+			log.Info("Another VirtualNetwork is the default virtual network", "virtualnetwork", conflict)
+			wantDefault = false
+			meta.SetStatusCondition(&vnet.Status.Conditions, metav1.Condition{
+				Type:               cloudflaredv1alpha1.DefaultNetworkCondition,
+				Status:             metav1.ConditionFalse,
+				ObservedGeneration: vnet.Generation,
+				Reason:             cloudflaredv1alpha1.DefaultConflictReason,
+				Message:            fmt.Sprintf("VirtualNetwork %s is the default virtual network of the account", conflict),
+			})
+		} else {
+			meta.SetStatusCondition(&vnet.Status.Conditions, metav1.Condition{
+				Type:               cloudflaredv1alpha1.DefaultNetworkCondition,
+				Status:             metav1.ConditionTrue,
+				ObservedGeneration: vnet.Generation,
+				Reason:             cloudflaredv1alpha1.ReconciledReason,
+			})
+		}
+	} else if meta.FindStatusCondition(vnet.Status.Conditions, cloudflaredv1alpha1.DefaultNetworkCondition) != nil {
+		meta.RemoveStatusCondition(&vnet.Status.Conditions, cloudflaredv1alpha1.DefaultNetworkCondition)
+	}
+
+	// Cloudflare always has one default virtual network, so it could only be moved to another.
+	isDefault := cfvnet.IsDefaultNetwork || wantDefault
+	if cfvnet.Comment != vnet.Spec.Comment || cfvnet.IsDefaultNetwork != isDefault {
+		log.Info("Updating cloudflare virtual network", "default", isDefault)
+		cfvnet.Comment = vnet.Spec.Comment
+		cfvnet.IsDefaultNetwork = isDefault
+		if err := cfclient.VirtualNetworks().Update(ctx, cfvnet); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	vnet.Status.ID = cfvnet.ID
+	vnet.Status.Default = cfvnet.IsDefaultNetwork
+	return ctrl.Result{RequeueAfter: virtualNetworkResyncPeriod}, nil
+}
+
+func (r *VirtualNetworkReconciler) reconcileDelete(ctx context.Context, cfclient cloudflare.Client, vnet *cloudflaredv1alpha1.VirtualNetwork) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	log.Info("Reconciling to deleting")
+
+	refs, err := r.virtualNetworkReferences(ctx, vnet)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if len(refs) != 0 {
+		log.Info("Virtual network is still in use", "references", refs)
+		r.Recorder.Eventf(vnet, corev1.EventTypeWarning, ReasonVirtualNetworkInUse, "Virtual network is still in use by %v", refs)
+		return ctrl.Result{RequeueAfter: virtualNetworkInUsePollPeriod}, nil
+	}
+
+	if vnet.Status.ID == "" {
+		controllerutil.RemoveFinalizer(vnet, cloudflaredv1alpha1.VirtualNetworkFinalizer)
+		return ctrl.Result{}, nil
+	}
+
+	// A virtual network that existed before the VirtualNetwork could be used outside of the cluster.
+	if !vnet.Status.Created {
+		log.Info("Leaving cloudflare virtual network not created for this VirtualNetwork")
+		r.Recorder.Eventf(vnet, corev1.EventTypeNormal, ReasonVirtualNetworkLeft, "Left cloudflare virtual network %s not created for this VirtualNetwork", vnet.Status.ID)
+		controllerutil.RemoveFinalizer(vnet, cloudflaredv1alpha1.VirtualNetworkFinalizer)
+		return ctrl.Result{}, nil
+	}
+
+	cfvnets, err := cfclient.VirtualNetworks().List(ctx, &cloudflare.VirtualNetworkListOptions{ID: vnet.Status.ID, IsDeleted: pointer.BoolPtr(false)})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	switch {
+	case len(cfvnets) == 0:
+		log.Info("Cloudflare virtual network not found or already deleted")
+	// Cloudflare refuses to delete the default virtual network of the account.
+	case cfvnets[0].IsDefaultNetwork:
+		log.Info("Leaving the default cloudflare virtual network")
+		r.Recorder.Eventf(vnet, corev1.EventTypeWarning, ReasonVirtualNetworkLeft,
+			"Left cloudflare virtual network %s since it is the default virtual network of the account", vnet.Status.ID)
+	default:
+		log.Info("Deleting cloudflare virtual network")
+		if err := cfclient.VirtualNetworks().Delete(ctx, vnet.Status.ID); err != nil && !cloudflare.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+
+		r.Recorder.Eventf(vnet, corev1.EventTypeNormal, ReasonVirtualNetworkDeleted, "Deleted cloudflare virtual network %s", vnet.Status.ID)
+	}
+
+	controllerutil.RemoveFinalizer(vnet, cloudflaredv1alpha1.VirtualNetworkFinalizer)
+	return ctrl.Result{}, nil
+}
+
+// defaultConflict returns the name of an older VirtualNetwork of the same account also desiring to be
+// the default virtual network, or empty string when there is none.
+func (r *VirtualNetworkReconciler) defaultConflict(ctx context.Context, cfclient cloudflare.Client, vnet *cloudflaredv1alpha1.VirtualNetwork) (string, error) {
+	vnetList := &cloudflaredv1alpha1.VirtualNetworkList{}
+	if err := r.Client.List(ctx, vnetList); err != nil {
+		return "", err
+	}
+
+	var conflict *cloudflaredv1alpha1.VirtualNetwork
+	for i := range vnetList.Items {
+		other := &vnetList.Items[i]
+		if other.Name == vnet.Name || !other.Spec.Default || !other.DeletionTimestamp.IsZero() || !olderVirtualNetwork(other, vnet) {
+			continue
+		}
+
+		sameAccount, err := r.sameAccount(ctx, cfclient, vnet, other)
+		if err != nil {
+			return "", err
+		}
+
+		if sameAccount && (conflict == nil || olderVirtualNetwork(other, conflict)) {
+			conflict = other
+		}
+	}
+
+	if conflict == nil {
+		return "", nil
+	}
+
+	return conflict.Name, nil
+}
+
+// sameAccount reports whether the other VirtualNetwork manages a virtual network of the account of cfclient.
+func (r *VirtualNetworkReconciler) sameAccount(ctx context.Context, cfclient cloudflare.Client, vnet, other *cloudflaredv1alpha1.VirtualNetwork) (bool, error) {
+	ref := other.Spec.ConfigurationRef
+	if ref == vnet.Spec.ConfigurationRef {
+		return true, nil
+	}
+
+	if ref.Namespace != r.ClusterResourceNamespace {
+		return false, nil
+	}
+
+	tunnelConfig := &cloudflaredv1alpha1.TunnelConfiguration{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}, tunnelConfig); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	othercfclient, err := r.ClientCache.Get(ctx, r.Client, ref.Namespace, tunnelConfig.Spec)
+	if err != nil {
+		// The VirtualNetwork can not manage its virtual network either.
+		return false, nil
+	}
+
+	return othercfclient.AccountID() == cfclient.AccountID(), nil
+}
+
+// olderVirtualNetwork reports whether a was created before b, the name breaks the tie.
+func olderVirtualNetwork(a, b *cloudflaredv1alpha1.VirtualNetwork) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+
+	return a.Name < b.Name
+}
+
+// virtualNetworkReferences returns the Tunnels and TunnelNetworkRoutes that still reference the VirtualNetwork.
+func (r *VirtualNetworkReconciler) virtualNetworkReferences(ctx context.Context, vnet *cloudflaredv1alpha1.VirtualNetwork) ([]string, error) {
+	var refs []string
+	tunnelList := &cloudflaredv1alpha1.TunnelList{}
+	if err := r.Client.List(ctx, tunnelList); err != nil {
+		return nil, err
+	}
+
+	for _, tunnel := range tunnelList.Items {
+		if tunnel.Spec.VirtualNetwork == vnet.Name {
+			refs = append(refs, "Tunnel "+tunnel.Namespace+"/"+tunnel.Name)
+		}
+	}
+
+	routeList := &cloudflaredv1alpha1.TunnelNetworkRouteList{}
+	if err := r.Client.List(ctx, routeList); err != nil {
+		return nil, err
+	}
+
+	for _, route := range routeList.Items {
+		// The routes could select the VirtualNetwork through their Tunnel, so the registered routes count too.
+		registered := vnet.Status.ID != "" && route.Status.VirtualNetworkID == vnet.Status.ID && len(route.Status.Routes) != 0
+		if route.Spec.VirtualNetwork == vnet.Name || registered {
+			refs = append(refs, "TunnelNetworkRoute "+route.Namespace+"/"+route.Name)
+		}
+	}
+
+	return refs, nil
+}
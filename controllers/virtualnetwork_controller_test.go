@@ -0,0 +1,256 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	cloudflaredv1alpha1 "github.com/prksu/cloudflared-controller/api/v1alpha1"
+	"github.com/prksu/cloudflared-controller/cloudflare"
+	"github.com/prksu/cloudflared-controller/util"
+)
+
+// newTestClientCache returns a ClientCache whose clients are served by given handler.
+func newTestClientCache(t *testing.T, handler http.Handler) *util.ClientCache {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	return util.NewClientCache(
+		cloudflare.WithBaseURL(srv.URL+"/client/v4"),
+		cloudflare.WithHTTPClient(srv.Client()),
+	)
+}
+
+// newTestTunnelConfiguration returns a TunnelConfiguration of test-account and test-zone.
+func newTestTunnelConfiguration(namespace string) *cloudflaredv1alpha1.TunnelConfiguration {
+	return &cloudflaredv1alpha1.TunnelConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: namespace},
+		Spec: cloudflaredv1alpha1.TunnelConfigurationSpec{
+			AccountID: "test-account",
+			ZoneID:    "test-zone",
+		},
+	}
+}
+
+func TestVirtualNetworkReconciler_Reconcile(t *testing.T) {
+	deletedAt := metav1.NewTime(time.Now())
+	createdAt := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+	tests := []struct {
+		name          string
+		vnet          *cloudflaredv1alpha1.VirtualNetwork
+		objs          []client.Object
+		cfvnets       []*cloudflare.VirtualNetwork
+		wantRequests  []string
+		wantResult    ctrl.Result
+		wantID        string
+		wantFinalizer bool
+		wantDefault   metav1.ConditionStatus
+	}{
+		{
+			name: "create",
+			vnet: &cloudflaredv1alpha1.VirtualNetwork{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster-a"},
+				Spec: cloudflaredv1alpha1.VirtualNetworkSpec{
+					ConfigurationRef: cloudflaredv1alpha1.TunnelConfigurationReference{Namespace: "cloudflared-system", Name: "config"},
+				},
+			},
+			wantRequests:  []string{"GET", "POST"},
+			wantResult:    ctrl.Result{RequeueAfter: virtualNetworkResyncPeriod},
+			wantID:        "vnet-id",
+			wantFinalizer: true,
+		},
+		{
+			name: "make default",
+			vnet: &cloudflaredv1alpha1.VirtualNetwork{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster-a"},
+				Spec: cloudflaredv1alpha1.VirtualNetworkSpec{
+					ConfigurationRef: cloudflaredv1alpha1.TunnelConfigurationReference{Namespace: "cloudflared-system", Name: "config"},
+					Default:          true,
+				},
+			},
+			cfvnets:       []*cloudflare.VirtualNetwork{{ID: "vnet-id", Name: "cluster-a"}},
+			wantRequests:  []string{"GET", "PATCH"},
+			wantResult:    ctrl.Result{RequeueAfter: virtualNetworkResyncPeriod},
+			wantID:        "vnet-id",
+			wantFinalizer: true,
+			wantDefault:   metav1.ConditionTrue,
+		},
+		{
+			name: "default conflict",
+			vnet: &cloudflaredv1alpha1.VirtualNetwork{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster-b", CreationTimestamp: metav1.NewTime(createdAt.Add(time.Minute))},
+				Spec: cloudflaredv1alpha1.VirtualNetworkSpec{
+					ConfigurationRef: cloudflaredv1alpha1.TunnelConfigurationReference{Namespace: "cloudflared-system", Name: "config"},
+					Default:          true,
+				},
+			},
+			objs: []client.Object{
+				&cloudflaredv1alpha1.VirtualNetwork{
+					ObjectMeta: metav1.ObjectMeta{Name: "cluster-a", CreationTimestamp: createdAt},
+					Spec: cloudflaredv1alpha1.VirtualNetworkSpec{
+						ConfigurationRef: cloudflaredv1alpha1.TunnelConfigurationReference{Namespace: "cloudflared-system", Name: "config"},
+						Default:          true,
+					},
+				},
+			},
+			cfvnets:       []*cloudflare.VirtualNetwork{{ID: "vnet-id", Name: "cluster-b"}},
+			wantRequests:  []string{"GET"},
+			wantResult:    ctrl.Result{RequeueAfter: virtualNetworkResyncPeriod},
+			wantID:        "vnet-id",
+			wantFinalizer: true,
+			wantDefault:   metav1.ConditionFalse,
+		},
+		{
+			name: "configuration of another namespace",
+			vnet: &cloudflaredv1alpha1.VirtualNetwork{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster-a"},
+				Spec: cloudflaredv1alpha1.VirtualNetworkSpec{
+					ConfigurationRef: cloudflaredv1alpha1.TunnelConfigurationReference{Namespace: "default", Name: "config"},
+				},
+			},
+		},
+		{
+			name: "delete in use",
+			vnet: &cloudflaredv1alpha1.VirtualNetwork{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster-a", DeletionTimestamp: &deletedAt, Finalizers: []string{cloudflaredv1alpha1.VirtualNetworkFinalizer}},
+				Spec: cloudflaredv1alpha1.VirtualNetworkSpec{
+					ConfigurationRef: cloudflaredv1alpha1.TunnelConfigurationReference{Namespace: "cloudflared-system", Name: "config"},
+				},
+				Status: cloudflaredv1alpha1.VirtualNetworkStatus{ID: "vnet-id"},
+			},
+			objs: []client.Object{
+				&cloudflaredv1alpha1.Tunnel{
+					ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+					Spec:       cloudflaredv1alpha1.TunnelSpec{VirtualNetwork: "cluster-a"},
+				},
+			},
+			wantResult:    ctrl.Result{RequeueAfter: virtualNetworkInUsePollPeriod},
+			wantID:        "vnet-id",
+			wantFinalizer: true,
+		},
+		{
+			name: "delete",
+			vnet: &cloudflaredv1alpha1.VirtualNetwork{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster-a", DeletionTimestamp: &deletedAt, Finalizers: []string{cloudflaredv1alpha1.VirtualNetworkFinalizer}},
+				Spec: cloudflaredv1alpha1.VirtualNetworkSpec{
+					ConfigurationRef: cloudflaredv1alpha1.TunnelConfigurationReference{Namespace: "cloudflared-system", Name: "config"},
+				},
+				Status: cloudflaredv1alpha1.VirtualNetworkStatus{ID: "vnet-id", Created: true},
+			},
+			cfvnets:      []*cloudflare.VirtualNetwork{{ID: "vnet-id", Name: "cluster-a"}},
+			wantRequests: []string{"GET", "DELETE"},
+			wantID:       "vnet-id",
+		},
+		{
+			name: "delete not created",
+			vnet: &cloudflaredv1alpha1.VirtualNetwork{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster-a", DeletionTimestamp: &deletedAt, Finalizers: []string{cloudflaredv1alpha1.VirtualNetworkFinalizer}},
+				Spec: cloudflaredv1alpha1.VirtualNetworkSpec{
+					ConfigurationRef: cloudflaredv1alpha1.TunnelConfigurationReference{Namespace: "cloudflared-system", Name: "config"},
+				},
+				Status: cloudflaredv1alpha1.VirtualNetworkStatus{ID: "vnet-id"},
+			},
+			cfvnets: []*cloudflare.VirtualNetwork{{ID: "vnet-id", Name: "cluster-a"}},
+			wantID:  "vnet-id",
+		},
+		{
+			name: "delete default",
+			vnet: &cloudflaredv1alpha1.VirtualNetwork{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster-a", DeletionTimestamp: &deletedAt, Finalizers: []string{cloudflaredv1alpha1.VirtualNetworkFinalizer}},
+				Spec: cloudflaredv1alpha1.VirtualNetworkSpec{
+					ConfigurationRef: cloudflaredv1alpha1.TunnelConfigurationReference{Namespace: "cloudflared-system", Name: "config"},
+				},
+				Status: cloudflaredv1alpha1.VirtualNetworkStatus{ID: "vnet-id", Created: true},
+			},
+			cfvnets:      []*cloudflare.VirtualNetwork{{ID: "vnet-id", Name: "cluster-a", IsDefaultNetwork: true}},
+			wantRequests: []string{"GET"},
+			wantID:       "vnet-id",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []string
+			cache := newTestClientCache(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r.Method)
+				switch r.Method {
+				case http.MethodGet:
+					writeCloudflareResponse(w, append([]*cloudflare.VirtualNetwork{}, tt.cfvnets...))
+				case http.MethodPost:
+					writeCloudflareResponse(w, &cloudflare.VirtualNetwork{ID: "vnet-id", Name: "cluster-a"})
+				default:
+					writeCloudflareResponse(w, struct{}{})
+				}
+			}))
+			objs := append([]client.Object{tt.vnet, newTestTunnelConfiguration("cloudflared-system"), newTestTunnelConfiguration("default")}, tt.objs...)
+			r := &VirtualNetworkReconciler{
+				Client:                   fake.NewClientBuilder().WithScheme(newTestScheme()).WithObjects(objs...).Build(),
+				Recorder:                 record.NewFakeRecorder(10),
+				ClientCache:              cache,
+				ClusterResourceNamespace: "cloudflared-system",
+			}
+
+			got, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(tt.vnet)})
+			if err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+
+			if got != tt.wantResult {
+				t.Errorf("Reconcile() = %v, want %v", got, tt.wantResult)
+			}
+
+			if !reflect.DeepEqual(requests, tt.wantRequests) {
+				t.Errorf("Reconcile() cloudflare requests = %v, want %v", requests, tt.wantRequests)
+			}
+
+			vnet := &cloudflaredv1alpha1.VirtualNetwork{}
+			if err := r.Client.Get(context.Background(), client.ObjectKeyFromObject(tt.vnet), vnet); err != nil {
+				t.Fatal(err)
+			}
+
+			if vnet.Status.ID != tt.wantID {
+				t.Errorf("Reconcile() status id = %v, want %v", vnet.Status.ID, tt.wantID)
+			}
+
+			if got := controllerutil.ContainsFinalizer(vnet, cloudflaredv1alpha1.VirtualNetworkFinalizer); got != tt.wantFinalizer {
+				t.Errorf("Reconcile() finalizer = %v, want %v", got, tt.wantFinalizer)
+			}
+
+			var gotDefault metav1.ConditionStatus
+			if cond := meta.FindStatusCondition(vnet.Status.Conditions, cloudflaredv1alpha1.DefaultNetworkCondition); cond != nil {
+				gotDefault = cond.Status
+			}
+
+			if gotDefault != tt.wantDefault {
+				t.Errorf("Reconcile() default network condition = %v, want %v", gotDefault, tt.wantDefault)
+			}
+		})
+	}
+}
@@ -10,9 +10,11 @@ require (
 	github.com/google/uuid v1.1.2
 	github.com/onsi/ginkgo v1.14.1
 	github.com/onsi/gomega v1.10.2
-	go.uber.org/multierr v1.5.0
+	github.com/prometheus/client_golang v1.7.1
 	go.uber.org/zap v1.15.0
 	golang.org/x/oauth2 v0.0.0-20200902213428-5d25da1a8d43
+	golang.org/x/time v0.0.0-20200630173020-3af7569d3a1e
+	gopkg.in/yaml.v2 v2.3.0
 	k8s.io/api v0.20.2
 	k8s.io/apimachinery v0.20.2
 	k8s.io/client-go v0.20.2
@@ -20,3 +22,19 @@ require (
 	sigs.k8s.io/controller-runtime v0.8.3
 	sigs.k8s.io/yaml v1.2.0
 )
+
+// The configuration of the Tunnel is validated with the ingress package of cloudflared, which has to be the
+// release run by the Tunnel pods, resources.CloudflaredVersion. The pinned commit is still 2021.5.10 and
+// must be bumped to the 2023.2.1 tag together with the image.
+//
+// There is no narrower package exposing the ingress parser, and through its config and tlsconfig imports
+// it pulls in the DNS proxy (coredns, caddy) and sentry modules. They grow go.sum and the manager binary although
+// the controller never runs them.
+//
+// urfave/cli/v2 is replaced with the same fork cloudflared replaces it with, the one cloudflared builds against.
+// go-oidc is pinned to the version cloudflared requires, otherwise k8s.io/apiserver selects
+// v2.1.0+incompatible that lacks the jose package imported by cloudflared/token.
+replace (
+	github.com/coreos/go-oidc => github.com/coreos/go-oidc v0.0.0-20171002155002-a93f71fdfe73
+	github.com/urfave/cli/v2 => github.com/ipostelnik/cli/v2 v2.3.1-0.20210324024421-b6ea8234fe3d
+)
@@ -17,15 +17,17 @@ limitations under the License.
 package main
 
 import (
-	"errors"
 	"flag"
+	"net/http"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	"golang.org/x/time/rate"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -36,6 +38,7 @@ import (
 	cloudflaredv1alpha1 "github.com/prksu/cloudflared-controller/api/v1alpha1"
 	"github.com/prksu/cloudflared-controller/cloudflare"
 	"github.com/prksu/cloudflared-controller/controllers"
+	"github.com/prksu/cloudflared-controller/util"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -55,11 +58,41 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var cloudflareCacheTTL time.Duration
+	var cloudflareRateLimit float64
+	var cloudflareRateLimitBurst int
+	var cloudflareAPIURL string
+	var cloudflareProxyURL string
+	var cloudflareCAFile string
+	var cloudflareUserAgent string
+	var cloudflareRequestTimeout time.Duration
+	var clusterResourceNamespace string
+	var tunnelResyncPeriod time.Duration
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.DurationVar(&cloudflareCacheTTL, "cloudflare-cache-ttl", 2*time.Minute,
+		"How long the cloudflare zone lookups, the tunnels and the resources fetched by name are cached. Zero disables the cache.")
+	flag.Float64Var(&cloudflareRateLimit, "cloudflare-rate-limit", 3,
+		"The maximum cloudflare api requests per second of each account, leaving the rest of the "+
+			"account budget (1200 requests per 5 minutes) for other tools. Zero disables the rate limiter.")
+	flag.IntVar(&cloudflareRateLimitBurst, "cloudflare-rate-limit-burst", 30,
+		"The maximum burst of cloudflare api requests of each account.")
+	flag.StringVar(&cloudflareAPIURL, "cloudflare-api-url", cloudflare.DefaultBaseURL, "The base url of the cloudflare api.")
+	flag.StringVar(&cloudflareProxyURL, "cloudflare-proxy-url", "",
+		"The proxy url the cloudflare api requests go through. The HTTPS_PROXY env var is used when it is not set.")
+	flag.StringVar(&cloudflareCAFile, "cloudflare-ca-file", "",
+		"The PEM encoded CA bundle trusted for the cloudflare api along with the system CAs, e.g. of a TLS inspecting egress proxy.")
+	flag.StringVar(&cloudflareUserAgent, "cloudflare-user-agent", "cloudflared-controller",
+		"The User-Agent header of the cloudflare api requests.")
+	flag.DurationVar(&cloudflareRequestTimeout, "cloudflare-request-timeout", 30*time.Second,
+		"The timeout of a single cloudflare api request.")
+	flag.StringVar(&clusterResourceNamespace, "cluster-resource-namespace", "cloudflared-system",
+		"The namespace of the TunnelConfigurations referenced by cluster scoped resources, e.g. VirtualNetwork.")
+	flag.DurationVar(&tunnelResyncPeriod, "tunnel-resync-period", 10*time.Minute,
+		"How often each Tunnel is reconciled to observe its connectors, costing two cloudflare api requests. Zero disables the resync.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -70,9 +103,7 @@ func main() {
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
 	if _, ok := os.LookupEnv(cloudflare.APITokenEnv); !ok {
-		err := errors.New("missing CF_API_TOKEN env var")
-		setupLog.Error(err, "unable to lookup cloudflare api token")
-		os.Exit(1)
+		setupLog.Info("CF_API_TOKEN env var is not set, every TunnelConfiguration has to reference its own api token or origincert")
 	}
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
@@ -88,10 +119,32 @@ func main() {
 		os.Exit(1)
 	}
 
+	cftransport, err := util.NewCloudflareTransport(cloudflareProxyURL, cloudflareCAFile)
+	if err != nil {
+		setupLog.Error(err, "unable to create cloudflare api transport")
+		os.Exit(1)
+	}
+
+	cfclientOpts := []cloudflare.ClientOption{
+		cloudflare.WithBaseURL(cloudflareAPIURL),
+		cloudflare.WithHTTPClient(&http.Client{Timeout: cloudflareRequestTimeout}),
+		cloudflare.WithTransport(cftransport),
+		cloudflare.WithUserAgent(cloudflareUserAgent),
+		cloudflare.WithLogger(ctrl.Log),
+		cloudflare.WithCacheTTL(cloudflareCacheTTL),
+	}
+	if cloudflareRateLimit > 0 {
+		rateLimiter := cloudflare.NewRateLimiter(rate.Limit(cloudflareRateLimit), cloudflareRateLimitBurst)
+		cfclientOpts = append(cfclientOpts, cloudflare.WithRateLimiter(rateLimiter))
+	}
+
+	cfclientCache := util.NewClientCache(cfclientOpts...)
 	if err = (&controllers.TunnelReconciler{
-		Client:   mgr.GetClient(),
-		Scheme:   mgr.GetScheme(),
-		Recorder: mgr.GetEventRecorderFor(controllers.TunnelControllerName),
+		Client:       mgr.GetClient(),
+		Scheme:       mgr.GetScheme(),
+		Recorder:     mgr.GetEventRecorderFor(controllers.TunnelControllerName),
+		ClientCache:  cfclientCache,
+		ResyncPeriod: tunnelResyncPeriod,
 	}).SetupWithManager(ctx, mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Tunnel")
 		os.Exit(1)
@@ -104,6 +157,35 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "Ingress")
 		os.Exit(1)
 	}
+	if err = (&controllers.TunnelNetworkRouteReconciler{
+		Client:      mgr.GetClient(),
+		Scheme:      mgr.GetScheme(),
+		Recorder:    mgr.GetEventRecorderFor(controllers.TunnelNetworkRouteControllerName),
+		ClientCache: cfclientCache,
+	}).SetupWithManager(ctx, mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "TunnelNetworkRoute")
+		os.Exit(1)
+	}
+	if err = (&controllers.VirtualNetworkReconciler{
+		Client:                   mgr.GetClient(),
+		Scheme:                   mgr.GetScheme(),
+		Recorder:                 mgr.GetEventRecorderFor(controllers.VirtualNetworkControllerName),
+		ClientCache:              cfclientCache,
+		ClusterResourceNamespace: clusterResourceNamespace,
+	}).SetupWithManager(ctx, mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "VirtualNetwork")
+		os.Exit(1)
+	}
+	if os.Getenv("ENABLE_WEBHOOKS") == "true" {
+		if err = (&cloudflaredv1alpha1.Tunnel{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Tunnel")
+			os.Exit(1)
+		}
+		if err = (&cloudflaredv1alpha1.TunnelConfiguration{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "TunnelConfiguration")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -0,0 +1,141 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cloudflaredv1alpha1 "github.com/prksu/cloudflared-controller/api/v1alpha1"
+	"github.com/prksu/cloudflared-controller/cloudflare"
+)
+
+// clientIdleTimeout is how long a client is kept without being used, so the clients of deleted resources
+// or namespaces are dropped. It is longer than the resync of the resources, which keeps using their client.
+const clientIdleTimeout = time.Hour
+
+// ClientCache shares the cloudflare clients between the reconciles of resources using the same
+// credentials. A client is only rebuilt when any of its credential Secrets changes, so both the
+// http connections and the cached lookups of the client are reused.
+type ClientCache struct {
+	mu      sync.Mutex
+	opts    []cloudflare.ClientOption
+	now     func() time.Time
+	clients map[string]clientCacheEntry
+}
+
+type clientCacheEntry struct {
+	version  string
+	client   cloudflare.Client
+	lastUsed time.Time
+}
+
+// NewClientCache creates ClientCache whose clients are built with the given options,
+// e.g. the logger, the lookup cache ttl and the rate limiter shared by every client.
+func NewClientCache(opts ...cloudflare.ClientOption) *ClientCache {
+	return &ClientCache{
+		opts:    opts,
+		now:     time.Now,
+		clients: make(map[string]clientCacheEntry),
+	}
+}
+
+// Get returns the cloudflare client of the TunnelConfigurationSpec of a resource in the given namespace.
+func (c *ClientCache) Get(ctx context.Context, crclient client.Client, namespace string, spec cloudflaredv1alpha1.TunnelConfigurationSpec) (cloudflare.Client, error) {
+	creds, err := getCloudflareCredentials(ctx, crclient, namespace, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	key := clientCacheKey(namespace, spec)
+	if cfclient, ok := c.lookup(key, creds.version); ok {
+		return cfclient, nil
+	}
+
+	// Building the client could look up the zone, so it is built without holding the lock
+	// to not block the reconciles of the other credential sets on the cloudflare api.
+	cfclient, err := newCloudflareClient(ctx, creds, spec, c.opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Keep the client of a concurrent Get that got in first, so every caller shares the same client.
+	if entry, ok := c.clients[key]; ok && entry.version == creds.version {
+		return entry.client, nil
+	}
+
+	// Idle clients are swept along the way.
+	now := c.now()
+	for k, entry := range c.clients {
+		if now.Sub(entry.lastUsed) > clientIdleTimeout {
+			delete(c.clients, k)
+		}
+	}
+
+	c.clients[key] = clientCacheEntry{version: creds.version, client: cfclient, lastUsed: now}
+	return cfclient, nil
+}
+
+// lookup returns the cached client of the credential set when it is built from the given version.
+func (c *ClientCache) lookup(key, version string) (cloudflare.Client, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.clients[key]
+	if !ok || entry.version != version {
+		return nil, false
+	}
+
+	now := c.now()
+	if now.Sub(entry.lastUsed) > clientIdleTimeout {
+		delete(c.clients, key)
+		return nil, false
+	}
+
+	entry.lastUsed = now
+	c.clients[key] = entry
+	return entry.client, true
+}
+
+// clientCacheKey identifies the credential set of the TunnelConfigurationSpec, the Secrets
+// are namespaced so the same reference from another namespace is another credential set.
+func clientCacheKey(namespace string, spec cloudflaredv1alpha1.TunnelConfigurationSpec) string {
+	key := struct {
+		Namespace         string
+		APITokenSecretRef *corev1.SecretKeySelector
+		OriginCert        *corev1.TypedLocalObjectReference
+		AccountID         string
+		ZoneID            string
+		Zone              string
+	}{
+		Namespace:         namespace,
+		APITokenSecretRef: spec.APITokenSecretRef,
+		OriginCert:        spec.OriginCert,
+		AccountID:         spec.AccountID,
+		ZoneID:            spec.ZoneID,
+		Zone:              spec.Zone,
+	}
+
+	raw, _ := json.Marshal(key)
+	return string(raw)
+}
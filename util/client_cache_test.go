@@ -0,0 +1,384 @@
+/*
+Copyright 2021 Ahmad Nurus S.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	cloudflaredv1alpha1 "github.com/prksu/cloudflared-controller/api/v1alpha1"
+	"github.com/prksu/cloudflared-controller/cloudflare"
+)
+
+func TestClientCache_Get(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+
+	spec := cloudflaredv1alpha1.TunnelConfigurationSpec{
+		APITokenSecretRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "my-api-token"},
+			Key:                  "token",
+		},
+		AccountID: "my-account-id",
+		ZoneID:    "my-zone-id",
+	}
+	tests := []struct {
+		name       string
+		namespace  string
+		spec       cloudflaredv1alpha1.TunnelConfigurationSpec
+		mutate     func(ctx context.Context, crclient client.Client) error
+		wantShared bool
+	}{
+		{
+			name:       "same credentials",
+			namespace:  "default",
+			spec:       spec,
+			wantShared: true,
+		},
+		{
+			name:      "secret changed",
+			namespace: "default",
+			spec:      spec,
+			mutate: func(ctx context.Context, crclient client.Client) error {
+				secret := &corev1.Secret{}
+				if err := crclient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "my-api-token"}, secret); err != nil {
+					return err
+				}
+
+				secret.Data["token"] = []byte("my-rotated-token")
+				return crclient.Update(ctx, secret)
+			},
+		},
+		{
+			name:      "secret of another namespace",
+			namespace: "other",
+			spec:      spec,
+		},
+		{
+			name:      "another zone",
+			namespace: "default",
+			spec: func() cloudflaredv1alpha1.TunnelConfigurationSpec {
+				spec := *spec.DeepCopy()
+				spec.ZoneID = "my-other-zone-id"
+				return spec
+			}(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			var objs []client.Object
+			for _, namespace := range []string{"default", "other"} {
+				objs = append(objs, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "my-api-token",
+						Namespace: namespace,
+					},
+					Data: map[string][]byte{
+						"token": []byte("my-token"),
+					},
+				})
+			}
+
+			crclient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+			cache := NewClientCache(cloudflare.WithCacheTTL(time.Minute))
+			first, err := cache.Get(ctx, crclient, "default", spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.mutate != nil {
+				if err := tt.mutate(ctx, crclient); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := cache.Get(ctx, crclient, tt.namespace, tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if shared := got == first; shared != tt.wantShared {
+				t.Errorf("ClientCache.Get() shared = %v, want %v", shared, tt.wantShared)
+			}
+		})
+	}
+}
+
+func TestClientCache_GetConcurrent(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+
+	// The zone lookup of the client built for zoneSpec blocks until released.
+	requested, release := make(chan struct{}, 2), make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested <- struct{}{}
+		<-release
+		raw, _ := json.Marshal([]*cloudflare.Zone{{ID: "my-zone-id", Name: "example.com"}})
+		_ = json.NewEncoder(w).Encode(cloudflare.Response{Success: true, Result: raw})
+	}))
+	defer srv.Close()
+
+	zoneSpec := cloudflaredv1alpha1.TunnelConfigurationSpec{AccountID: "my-account-id", Zone: "example.com"}
+	zoneIDSpec := cloudflaredv1alpha1.TunnelConfigurationSpec{AccountID: "my-account-id", ZoneID: "my-zone-id"}
+	ctx := context.Background()
+	crclient := fake.NewClientBuilder().WithScheme(scheme).Build()
+	cache := NewClientCache(cloudflare.WithBaseURL(srv.URL+"/client/v4"), cloudflare.WithHTTPClient(srv.Client()))
+
+	type result struct {
+		client cloudflare.Client
+		err    error
+	}
+	blocked := make(chan result, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			cfclient, err := cache.Get(ctx, crclient, "default", zoneSpec)
+			blocked <- result{cfclient, err}
+		}()
+	}
+
+	<-requested
+	done := make(chan error)
+	go func() {
+		_, err := cache.Get(ctx, crclient, "default", zoneIDSpec)
+		done <- err
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("ClientCache.Get() is blocked by the zone lookup of another credential set")
+	}
+
+	close(release)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, second := <-blocked, <-blocked
+	if first.err != nil || second.err != nil {
+		t.Fatal(first.err, second.err)
+	}
+
+	if first.client != second.client {
+		t.Errorf("ClientCache.Get() concurrent callers got different clients")
+	}
+
+	if got, _ := cache.Get(ctx, crclient, "default", zoneSpec); got != first.client {
+		t.Errorf("ClientCache.Get() does not reuse the cached client")
+	}
+}
+
+func TestClientCache_GetAccountAndZone(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+
+	tests := []struct {
+		name          string
+		spec          cloudflaredv1alpha1.TunnelConfigurationSpec
+		wantAccountID string
+		wantZoneID    string
+		wantErr       error
+	}{
+		{
+			name: "account and zone id",
+			spec: cloudflaredv1alpha1.TunnelConfigurationSpec{
+				AccountID: "my-account-id",
+				ZoneID:    "my-zone-id",
+			},
+			wantAccountID: "my-account-id",
+			wantZoneID:    "my-zone-id",
+		},
+		{
+			name: "missing account id",
+			spec: cloudflaredv1alpha1.TunnelConfigurationSpec{
+				ZoneID: "my-zone-id",
+			},
+			wantErr: ErrMissingAccountID,
+		},
+		{
+			name: "missing zone",
+			spec: cloudflaredv1alpha1.TunnelConfigurationSpec{
+				AccountID: "my-account-id",
+			},
+			wantErr: ErrMissingZone,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crclient := fake.NewClientBuilder().WithScheme(scheme).Build()
+			got, err := NewClientCache().Get(context.Background(), crclient, "default", tt.spec)
+			if err != tt.wantErr {
+				t.Errorf("ClientCache.Get() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if err != nil {
+				return
+			}
+
+			if got.AccountID() != tt.wantAccountID || got.ZoneID() != tt.wantZoneID {
+				t.Errorf("ClientCache.Get() = account %q zone %q, want account %q zone %q", got.AccountID(), got.ZoneID(), tt.wantAccountID, tt.wantZoneID)
+			}
+		})
+	}
+}
+
+func TestClientCache_GetAPIToken(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	t.Setenv(cloudflare.APITokenEnv, "default-token")
+
+	crclient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "my-api-token",
+				Namespace: "default",
+			},
+			Data: map[string][]byte{
+				"token": []byte("my-token"),
+			},
+		},
+	).Build()
+
+	tests := []struct {
+		name      string
+		namespace string
+		ref       *corev1.SecretKeySelector
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:      "secret",
+			namespace: "default",
+			ref: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "my-api-token"},
+				Key:                  "token",
+			},
+			want: "Bearer my-token",
+		},
+		{
+			name:      "env fallback",
+			namespace: "default",
+			want:      "Bearer default-token",
+		},
+		{
+			name:      "missing key",
+			namespace: "default",
+			ref: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "my-api-token"},
+				Key:                  "api-token",
+			},
+			wantErr: true,
+		},
+		{
+			name:      "secret of another namespace",
+			namespace: "other",
+			ref: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "my-api-token"},
+				Key:                  "token",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Get("Authorization")
+				raw, _ := json.Marshal(&cloudflare.Zone{ID: "my-zone-id", Name: "example.com"})
+				_ = json.NewEncoder(w).Encode(cloudflare.Response{Success: true, Result: raw})
+			}))
+			defer srv.Close()
+
+			cache := NewClientCache(cloudflare.WithBaseURL(srv.URL+"/client/v4"), cloudflare.WithHTTPClient(srv.Client()))
+			spec := cloudflaredv1alpha1.TunnelConfigurationSpec{APITokenSecretRef: tt.ref, AccountID: "my-account-id", ZoneID: "my-zone-id"}
+			cfclient, err := cache.Get(context.Background(), crclient, tt.namespace, spec)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ClientCache.Get() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if err != nil {
+				return
+			}
+
+			if _, err := cfclient.Zones().Get(context.Background(), "my-zone-id"); err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Errorf("ClientCache.Get() authorization = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientCache_GetIdle(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+
+	spec := cloudflaredv1alpha1.TunnelConfigurationSpec{AccountID: "my-account-id", ZoneID: "my-zone-id"}
+	otherSpec := cloudflaredv1alpha1.TunnelConfigurationSpec{AccountID: "my-account-id", ZoneID: "my-other-zone-id"}
+	ctx := context.Background()
+	crclient := fake.NewClientBuilder().WithScheme(scheme).Build()
+	cache := NewClientCache()
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	first, err := cache.Get(ctx, crclient, "default", spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cache.Get(ctx, crclient, "default", otherSpec); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(clientIdleTimeout / 2)
+	if got, _ := cache.Get(ctx, crclient, "default", spec); got != first {
+		t.Errorf("ClientCache.Get() does not reuse the client in use")
+	}
+
+	// The client of otherSpec is swept along with the insertion of another credential set.
+	now = now.Add(clientIdleTimeout/2 + time.Minute)
+	if _, err := cache.Get(ctx, crclient, "other", spec); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := len(cache.clients); got != 2 {
+		t.Errorf("ClientCache.Get() clients = %v, want 2", got)
+	}
+
+	now = now.Add(clientIdleTimeout + time.Minute)
+	if got, _ := cache.Get(ctx, crclient, "default", spec); got == first {
+		t.Errorf("ClientCache.Get() reuses the idle client")
+	}
+}
@@ -29,6 +29,7 @@ import (
 type IngressResourceGetter interface {
 	Tunnel() *cloudflaredv1alpha1.Tunnel
 	TunnelIngressRules() []cloudflaredv1alpha1.TunnelIngressRule
+	TunnelLoadBalancer() *cloudflaredv1alpha1.TunnelLoadBalancer
 }
 
 type ingressResource struct {
@@ -110,3 +111,16 @@ func (r ingressResource) TunnelIngressRules() []cloudflaredv1alpha1.TunnelIngres
 
 	return tirList
 }
+
+// TunnelLoadBalancer returns the Load Balancer routing from the Ingress annotation,
+// or nil when the Ingress hostnames are routed by DNS CNAME record.
+func (r ingressResource) TunnelLoadBalancer() *cloudflaredv1alpha1.TunnelLoadBalancer {
+	pool, ok := r.Annotations[cloudflaredv1alpha1.LoadBalancerPoolAnnotation]
+	if !ok || pool == "" {
+		return nil
+	}
+
+	return &cloudflaredv1alpha1.TunnelLoadBalancer{
+		Pool: pool,
+	}
+}
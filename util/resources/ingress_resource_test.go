@@ -109,3 +109,45 @@ func Test_ingressResource_TunnelIngressRules(t *testing.T) {
 		})
 	}
 }
+
+func Test_ingressResource_TunnelLoadBalancer(t *testing.T) {
+	type fields struct {
+		Ingress *networkingv1.Ingress
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		want   *cloudflaredv1alpha1.TunnelLoadBalancer
+	}{
+		{
+			name: "default (should be routed by DNS)",
+			fields: fields{
+				Ingress: &networkingv1.Ingress{},
+			},
+			want: nil,
+		},
+		{
+			name: "with load balancer pool annotation",
+			fields: fields{
+				Ingress: &networkingv1.Ingress{
+					ObjectMeta: metav1.ObjectMeta{
+						Annotations: map[string]string{
+							cloudflaredv1alpha1.LoadBalancerPoolAnnotation: "my-pool",
+						},
+					},
+				},
+			},
+			want: &cloudflaredv1alpha1.TunnelLoadBalancer{
+				Pool: "my-pool",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewIngressResources(tt.fields.Ingress)
+			if got := r.TunnelLoadBalancer(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ingressResource.TunnelLoadBalancer() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -17,6 +17,14 @@ limitations under the License.
 package resources
 
 import (
+	"encoding/json"
+	"hash/fnv"
+	"strconv"
+	"time"
+
+	cfconfig "github.com/cloudflare/cloudflared/config"
+	"github.com/cloudflare/cloudflared/ingress"
+	yamlv2 "gopkg.in/yaml.v2"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -25,18 +33,45 @@ import (
 	"sigs.k8s.io/yaml"
 
 	cloudflaredv1alpha1 "github.com/prksu/cloudflared-controller/api/v1alpha1"
+	"github.com/prksu/cloudflared-controller/cloudflare"
+)
+
+const (
+	// PodTemplateHashAnnotation is the pod template annotation of the hash of the desired pod template.
+	// The existing pod template is replaced only when the hash changes.
+	PodTemplateHashAnnotation = "cloudflared.cloudflare.com/pod-template-hash"
+	// TunnelIDAnnotation is the token Secret annotation of the tunnel ID the token belongs to.
+	TunnelIDAnnotation = "cloudflared.cloudflare.com/tunnel-id"
+
+	// TokenSecretKey is the key of tunnel token in the token Secret.
+	TokenSecretKey = "token"
+
+	// CloudflaredVersion is the cloudflared release run by the Tunnel pods. ValidateConfiguration parses the
+	// rules with the github.com/cloudflare/cloudflared module, which go.mod must pin to the same release.
+	CloudflaredVersion = "2023.2.1"
+
+	cloudflaredImage = "cloudflare/cloudflared:" + CloudflaredVersion
 )
 
 type TunnelResourceGetter interface {
 	TunnelName() string
 	SecretName() string
+	TokenSecretName() string
 	ConfigMapName() string
+	CommonLabels() map[string]string
+	IsRemotelyManaged() bool
+	RunsWithCredentialsFile() bool
+	CredentialsFileKey() string
 
 	Secret(data map[string][]byte) *corev1.Secret
+	TokenSecret(tunnelID, token string) *corev1.Secret
 	ConfigMap() *corev1.ConfigMap
 	ConfigMapData() (map[string]string, error)
+	ValidateConfiguration() error
+	RemoteConfiguration() (*cloudflare.TunnelConfiguration, error)
 
 	Deployment() *appsv1.Deployment
+	PodTemplate() corev1.PodTemplateSpec
 }
 
 type tunnelResource struct {
@@ -49,9 +84,25 @@ func NewTunnelResources(tunnel *cloudflaredv1alpha1.Tunnel) TunnelResourceGetter
 	}
 }
 
-func (r tunnelResource) TunnelName() string    { return "k8s-" + r.Name }
-func (r tunnelResource) SecretName() string    { return r.Name + "-secret" }
-func (r tunnelResource) ConfigMapName() string { return r.Name + "-config" }
+func (r tunnelResource) TunnelName() string      { return "k8s-" + r.Name }
+func (r tunnelResource) SecretName() string      { return r.Name + "-secret" }
+func (r tunnelResource) TokenSecretName() string { return r.Name + "-token" }
+func (r tunnelResource) ConfigMapName() string   { return r.Name + "-config" }
+
+// IsRemotelyManaged reports whether the Tunnel configuration is served by Cloudflare.
+func (r tunnelResource) IsRemotelyManaged() bool {
+	return r.Spec.ConfigSource == cloudflaredv1alpha1.TunnelConfigSourceCloudflare
+}
+
+// RunsWithCredentialsFile reports whether cloudflared runs with the credentials file instead of the tunnel token.
+func (r tunnelResource) RunsWithCredentialsFile() bool {
+	return r.Spec.CredentialsSource == cloudflaredv1alpha1.TunnelCredentialsSourceFile
+}
+
+// CredentialsFileKey returns the key of the credentials file in the Secret.
+func (r tunnelResource) CredentialsFileKey() string {
+	return r.TunnelName() + ".json"
+}
 
 func (r tunnelResource) CommonLabels() map[string]string {
 	return map[string]string{
@@ -71,6 +122,23 @@ func (r tunnelResource) Secret(data map[string][]byte) *corev1.Secret {
 	}
 }
 
+// TokenSecret returns the Secret holding only the token to run the tunnel.
+func (r tunnelResource) TokenSecret(tunnelID, token string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      r.TokenSecretName(),
+			Namespace: r.Namespace,
+			Labels:    labels.Merge(r.Labels, r.CommonLabels()),
+			Annotations: map[string]string{
+				TunnelIDAnnotation: tunnelID,
+			},
+		},
+		Data: map[string][]byte{
+			TokenSecretKey: []byte(token),
+		},
+	}
+}
+
 func (r tunnelResource) ConfigMap() *corev1.ConfigMap {
 	return &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
@@ -81,18 +149,26 @@ func (r tunnelResource) ConfigMap() *corev1.ConfigMap {
 	}
 }
 
+type warpRouting struct {
+	Enabled bool `json:"enabled"`
+}
+
 func (r tunnelResource) ConfigMapData() (map[string]string, error) {
 	data := make(map[string]string)
 	config := struct {
-		Tunnel          string                                   `json:"tunnel,omitempty"`
-		CredentialsFile string                                   `json:"credentials-file,omitempty"`
-		Ingress         []cloudflaredv1alpha1.TunnelIngressRule  `json:"ingress,omitempty"`
-		OriginRequest   *cloudflaredv1alpha1.TunnelOriginRequest `json:"originRequest,omitempty"`
+		Tunnel        string                                   `json:"tunnel,omitempty"`
+		Ingress       []cloudflaredv1alpha1.TunnelIngressRule  `json:"ingress,omitempty"`
+		OriginRequest *cloudflaredv1alpha1.TunnelOriginRequest `json:"originRequest,omitempty"`
+		WarpRouting   *warpRouting                             `json:"warp-routing,omitempty"`
 	}{
-		Tunnel:          r.TunnelName(),
-		CredentialsFile: "/etc/cloudflared/" + r.TunnelName() + ".json",
-		Ingress:         r.Spec.IngressRules,
-		OriginRequest:   r.Spec.OriginRequest,
+		Tunnel:        r.TunnelName(),
+		Ingress:       r.Spec.IngressRules,
+		OriginRequest: r.Spec.OriginRequest,
+	}
+
+	// Private network routes are only served by cloudflared with warp-routing enabled.
+	if len(r.Status.NetworkRoutes) != 0 {
+		config.WarpRouting = &warpRouting{Enabled: true}
 	}
 
 	b, err := yaml.Marshal(config)
@@ -100,6 +176,91 @@ func (r tunnelResource) ConfigMapData() (map[string]string, error) {
 	return data, err
 }
 
+// ValidateConfiguration parses the rendered configuration the way cloudflared does on startup, so an
+// invalid configuration is caught before it reaches cloudflared. A Tunnel without ingress rules is not validated.
+func (r tunnelResource) ValidateConfiguration() error {
+	if len(r.Spec.IngressRules) == 0 {
+		return nil
+	}
+
+	data, err := r.ConfigMapData()
+	if err != nil {
+		return err
+	}
+
+	// cloudflared reads its configuration with yaml.v2, which parses the durations unlike sigs.k8s.io/yaml.
+	config := &cfconfig.Configuration{}
+	if err := yamlv2.Unmarshal([]byte(data["config.yaml"]), config); err != nil {
+		return err
+	}
+
+	_, err = ingress.ParseIngress(config)
+	return err
+}
+
+// RemoteConfiguration returns the Tunnel configuration pushed to Cloudflare when the Tunnel is remotely managed.
+func (r tunnelResource) RemoteConfiguration() (*cloudflare.TunnelConfiguration, error) {
+	config := &cloudflare.TunnelConfiguration{}
+	for _, rule := range r.Spec.IngressRules {
+		config.Ingress = append(config.Ingress, cloudflare.TunnelIngressRule{
+			Hostname: rule.Hostname,
+			Path:     rule.Path,
+			Service:  rule.Service,
+		})
+	}
+
+	originRequest, err := remoteOriginRequest(r.Spec.OriginRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	config.OriginRequest = originRequest
+	if len(r.Status.NetworkRoutes) != 0 {
+		config.WarpRouting = &cloudflare.TunnelWarpRouting{Enabled: true}
+	}
+
+	return config, nil
+}
+
+// remoteOriginRequest converts the originRequest configurations, whose timeouts
+// are durations, into the Cloudflare one whose timeouts are in seconds.
+func remoteOriginRequest(in *cloudflaredv1alpha1.TunnelOriginRequest) (*cloudflare.TunnelOriginRequest, error) {
+	if in == nil {
+		return nil, nil
+	}
+
+	out := &cloudflare.TunnelOriginRequest{
+		KeepAliveConnections:   int(in.KeepAliveConnections),
+		DisableChunkedEncoding: in.DisableChunkedEncoding,
+		HTTPHostHeader:         in.HTTPHostHeader,
+		NoTLSVerify:            in.NoTLSVerify,
+		OriginServerName:       in.OriginServerName,
+	}
+
+	for _, timeout := range []struct {
+		value string
+		out   *int
+	}{
+		{in.ConnectTimeout, &out.ConnectTimeout},
+		{in.TLSTimeout, &out.TLSTimeout},
+		{in.TCPKeepAlive, &out.TCPKeepAlive},
+		{in.KeepAliveTimeout, &out.KeepAliveTimeout},
+	} {
+		if timeout.value == "" {
+			continue
+		}
+
+		d, err := time.ParseDuration(timeout.value)
+		if err != nil {
+			return nil, err
+		}
+
+		*timeout.out = int(d.Seconds())
+	}
+
+	return out, nil
+}
+
 func (r tunnelResource) Deployment() *appsv1.Deployment {
 	return &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
@@ -115,8 +276,10 @@ func (r tunnelResource) Deployment() *appsv1.Deployment {
 	}
 }
 
+// PodTemplate returns the pod template running cloudflared with the tunnel token, or the credentials
+// file when opted in. The origincert is never mounted, so a compromised pod could only run this tunnel.
 func (r tunnelResource) PodTemplate() corev1.PodTemplateSpec {
-	return corev1.PodTemplateSpec{
+	template := corev1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
 			Labels: r.CommonLabels(),
 		},
@@ -124,62 +287,84 @@ func (r tunnelResource) PodTemplate() corev1.PodTemplateSpec {
 			Containers: []corev1.Container{
 				{
 					Name:  "cloudflared",
-					Image: "cloudflare/cloudflared:2021.5.10",
+					Image: cloudflaredImage,
 					Env: []corev1.EnvVar{
 						{
-							Name:  "TUNNEL_ORIGIN_CERT",
-							Value: "/etc/cloudflared/cert.pem",
-						},
-					},
-					Command: []string{"cloudflared", "tunnel"},
-					Args:    []string{"--no-autoupdate", "--config", "/.cloudflared/config.yaml", "run"},
-					VolumeMounts: []corev1.VolumeMount{
-						{
-							Name:      "secret",
-							MountPath: "/etc/cloudflared",
-						},
-						{
-							Name:      "config",
-							MountPath: "/.cloudflared",
-						},
-					},
-				},
-			},
-			Volumes: []corev1.Volume{
-				{
-					Name: "secret",
-					VolumeSource: corev1.VolumeSource{
-						Projected: &corev1.ProjectedVolumeSource{
-							Sources: []corev1.VolumeProjection{
-								{
-									Secret: &corev1.SecretProjection{
-										LocalObjectReference: corev1.LocalObjectReference{
-											Name: r.SecretName(),
-										},
-									},
-								},
-								{
-									Secret: &corev1.SecretProjection{
-										LocalObjectReference: corev1.LocalObjectReference{
-											Name: r.Spec.OriginCert.Name,
-										},
+							Name: "TUNNEL_TOKEN",
+							ValueFrom: &corev1.EnvVarSource{
+								SecretKeyRef: &corev1.SecretKeySelector{
+									LocalObjectReference: corev1.LocalObjectReference{
+										Name: r.TokenSecretName(),
 									},
+									Key: TokenSecretKey,
 								},
 							},
 						},
 					},
+					Command: []string{"cloudflared", "tunnel"},
+					Args:    []string{"--no-autoupdate", "run"},
 				},
-				{
-					Name: "config",
-					VolumeSource: corev1.VolumeSource{
-						ConfigMap: &corev1.ConfigMapVolumeSource{
-							LocalObjectReference: corev1.LocalObjectReference{
-								Name: r.ConfigMapName(),
-							},
-						},
+			},
+		},
+	}
+
+	// A remotely managed Tunnel gets the configuration from Cloudflare,
+	// otherwise it is read from the ConfigMap.
+	container := &template.Spec.Containers[0]
+	if !r.IsRemotelyManaged() {
+		container.Args = []string{"--no-autoupdate", "--config", "/.cloudflared/config.yaml", "run"}
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      "config",
+			MountPath: "/.cloudflared",
+		})
+		template.Spec.Volumes = append(template.Spec.Volumes, corev1.Volume{
+			Name: "config",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: r.ConfigMapName(),
 					},
 				},
 			},
-		},
+		})
 	}
+
+	// The credentials file holds the tunnel name, so cloudflared resolves the
+	// tunnel by name from the file rather than through the api.
+	if r.RunsWithCredentialsFile() {
+		container.Env = []corev1.EnvVar{
+			{
+				Name:  "TUNNEL_CRED_FILE",
+				Value: "/etc/cloudflared/" + r.CredentialsFileKey(),
+			},
+		}
+		container.Args = append(container.Args, r.TunnelName())
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      "credentials",
+			MountPath: "/etc/cloudflared",
+			ReadOnly:  true,
+		})
+		template.Spec.Volumes = append(template.Spec.Volumes, corev1.Volume{
+			Name: "credentials",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: r.SecretName(),
+				},
+			},
+		})
+	}
+
+	template.Annotations = map[string]string{
+		PodTemplateHashAnnotation: podTemplateHash(template),
+	}
+
+	return template
+}
+
+// podTemplateHash returns the hash of given pod template.
+func podTemplateHash(template corev1.PodTemplateSpec) string {
+	b, _ := json.Marshal(template)
+	hasher := fnv.New32a()
+	_, _ = hasher.Write(b)
+	return strconv.FormatUint(uint64(hasher.Sum32()), 16)
 }
@@ -21,6 +21,7 @@ import (
 	"testing"
 
 	cloudflaredv1alpha1 "github.com/prksu/cloudflared-controller/api/v1alpha1"
+	"github.com/prksu/cloudflared-controller/cloudflare"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -135,9 +136,9 @@ func Test_tunnelResource_ConfigMapData(t *testing.T) {
 		name   string
 		fields fields
 		want   struct {
-			Tunnel          string                                  `json:"tunnel,omitempty"`
-			CredentialsFile string                                  `json:"credentials-file,omitempty"`
-			Ingress         []cloudflaredv1alpha1.TunnelIngressRule `json:"ingress,omitempty"`
+			Tunnel      string                                  `json:"tunnel,omitempty"`
+			Ingress     []cloudflaredv1alpha1.TunnelIngressRule `json:"ingress,omitempty"`
+			WarpRouting *warpRouting                            `json:"warp-routing,omitempty"`
 		}
 		wantErr bool
 	}{
@@ -162,12 +163,11 @@ func Test_tunnelResource_ConfigMapData(t *testing.T) {
 				},
 			},
 			want: struct {
-				Tunnel          string                                  `json:"tunnel,omitempty"`
-				CredentialsFile string                                  `json:"credentials-file,omitempty"`
-				Ingress         []cloudflaredv1alpha1.TunnelIngressRule `json:"ingress,omitempty"`
+				Tunnel      string                                  `json:"tunnel,omitempty"`
+				Ingress     []cloudflaredv1alpha1.TunnelIngressRule `json:"ingress,omitempty"`
+				WarpRouting *warpRouting                            `json:"warp-routing,omitempty"`
 			}{
-				Tunnel:          "k8s-test-tunnel",
-				CredentialsFile: "/etc/cloudflared/k8s-test-tunnel.json",
+				Tunnel: "k8s-test-tunnel",
 				Ingress: []cloudflaredv1alpha1.TunnelIngressRule{
 					{
 						Service: "http://foo:8000",
@@ -175,6 +175,40 @@ func Test_tunnelResource_ConfigMapData(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "with network routes",
+			fields: fields{
+				Tunnel: &cloudflaredv1alpha1.Tunnel{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-tunnel",
+						Namespace: "default",
+					},
+					Spec: cloudflaredv1alpha1.TunnelSpec{
+						IngressRules: []cloudflaredv1alpha1.TunnelIngressRule{
+							{
+								Service: "http://foo:8000",
+							},
+						},
+					},
+					Status: cloudflaredv1alpha1.TunnelStatus{
+						NetworkRoutes: []string{"10.96.0.0/12"},
+					},
+				},
+			},
+			want: struct {
+				Tunnel      string                                  `json:"tunnel,omitempty"`
+				Ingress     []cloudflaredv1alpha1.TunnelIngressRule `json:"ingress,omitempty"`
+				WarpRouting *warpRouting                            `json:"warp-routing,omitempty"`
+			}{
+				Tunnel: "k8s-test-tunnel",
+				Ingress: []cloudflaredv1alpha1.TunnelIngressRule{
+					{
+						Service: "http://foo:8000",
+					},
+				},
+				WarpRouting: &warpRouting{Enabled: true},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -256,20 +290,23 @@ func Test_tunnelResource_Deployment(t *testing.T) {
 							Containers: []corev1.Container{
 								{
 									Name:  "cloudflared",
-									Image: "cloudflare/cloudflared:2021.5.10",
+									Image: "cloudflare/cloudflared:2023.2.1",
 									Env: []corev1.EnvVar{
 										{
-											Name:  "TUNNEL_ORIGIN_CERT",
-											Value: "/etc/cloudflared/cert.pem",
+											Name: "TUNNEL_TOKEN",
+											ValueFrom: &corev1.EnvVarSource{
+												SecretKeyRef: &corev1.SecretKeySelector{
+													LocalObjectReference: corev1.LocalObjectReference{
+														Name: "test-tunnel-token",
+													},
+													Key: "token",
+												},
+											},
 										},
 									},
 									Command: []string{"cloudflared", "tunnel"},
 									Args:    []string{"--no-autoupdate", "--config", "/.cloudflared/config.yaml", "run"},
 									VolumeMounts: []corev1.VolumeMount{
-										{
-											Name:      "secret",
-											MountPath: "/etc/cloudflared",
-										},
 										{
 											Name:      "config",
 											MountPath: "/.cloudflared",
@@ -279,28 +316,151 @@ func Test_tunnelResource_Deployment(t *testing.T) {
 							},
 							Volumes: []corev1.Volume{
 								{
-									Name: "secret",
+									Name: "config",
 									VolumeSource: corev1.VolumeSource{
-										Projected: &corev1.ProjectedVolumeSource{
-											Sources: []corev1.VolumeProjection{
-												{
-													Secret: &corev1.SecretProjection{
-														LocalObjectReference: corev1.LocalObjectReference{
-															Name: "test-tunnel-secret",
-														},
-													},
-												},
-												{
-													Secret: &corev1.SecretProjection{
-														LocalObjectReference: corev1.LocalObjectReference{
-															Name: "test-origincert",
-														},
+										ConfigMap: &corev1.ConfigMapVolumeSource{
+											LocalObjectReference: corev1.LocalObjectReference{
+												Name: "test-tunnel-config",
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "remotely managed",
+			fields: fields{
+				Tunnel: &cloudflaredv1alpha1.Tunnel{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-tunnel",
+						Namespace: "default",
+					},
+					Spec: cloudflaredv1alpha1.TunnelSpec{
+						TunnelConfigurationSpec: cloudflaredv1alpha1.TunnelConfigurationSpec{
+							OriginCert: &corev1.TypedLocalObjectReference{
+								Kind: "Secret",
+								Name: "test-origincert",
+							},
+							ConfigSource: cloudflaredv1alpha1.TunnelConfigSourceCloudflare,
+						},
+					},
+				},
+			},
+			want: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-tunnel",
+					Namespace: "default",
+					Labels: map[string]string{
+						"cloudflared.cloudflare.com/managed-by": "test-tunnel",
+					},
+				},
+				Spec: appsv1.DeploymentSpec{
+					Replicas: pointer.Int32Ptr(0),
+					Selector: metav1.SetAsLabelSelector(
+						map[string]string{
+							"cloudflared.cloudflare.com/managed-by": "test-tunnel",
+						},
+					),
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"cloudflared.cloudflare.com/managed-by": "test-tunnel",
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "cloudflared",
+									Image: "cloudflare/cloudflared:2023.2.1",
+									Env: []corev1.EnvVar{
+										{
+											Name: "TUNNEL_TOKEN",
+											ValueFrom: &corev1.EnvVarSource{
+												SecretKeyRef: &corev1.SecretKeySelector{
+													LocalObjectReference: corev1.LocalObjectReference{
+														Name: "test-tunnel-token",
 													},
+													Key: "token",
 												},
 											},
 										},
 									},
+									Command: []string{"cloudflared", "tunnel"},
+									Args:    []string{"--no-autoupdate", "run"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "credentials file",
+			fields: fields{
+				Tunnel: &cloudflaredv1alpha1.Tunnel{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-tunnel",
+						Namespace: "default",
+					},
+					Spec: cloudflaredv1alpha1.TunnelSpec{
+						TunnelConfigurationSpec: cloudflaredv1alpha1.TunnelConfigurationSpec{
+							CredentialsSource: cloudflaredv1alpha1.TunnelCredentialsSourceFile,
+						},
+					},
+				},
+			},
+			want: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-tunnel",
+					Namespace: "default",
+					Labels: map[string]string{
+						"cloudflared.cloudflare.com/managed-by": "test-tunnel",
+					},
+				},
+				Spec: appsv1.DeploymentSpec{
+					Replicas: pointer.Int32Ptr(0),
+					Selector: metav1.SetAsLabelSelector(
+						map[string]string{
+							"cloudflared.cloudflare.com/managed-by": "test-tunnel",
+						},
+					),
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{
+								"cloudflared.cloudflare.com/managed-by": "test-tunnel",
+							},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:  "cloudflared",
+									Image: "cloudflare/cloudflared:2023.2.1",
+									Env: []corev1.EnvVar{
+										{
+											Name:  "TUNNEL_CRED_FILE",
+											Value: "/etc/cloudflared/k8s-test-tunnel.json",
+										},
+									},
+									Command: []string{"cloudflared", "tunnel"},
+									Args:    []string{"--no-autoupdate", "--config", "/.cloudflared/config.yaml", "run", "k8s-test-tunnel"},
+									VolumeMounts: []corev1.VolumeMount{
+										{
+											Name:      "config",
+											MountPath: "/.cloudflared",
+										},
+										{
+											Name:      "credentials",
+											MountPath: "/etc/cloudflared",
+											ReadOnly:  true,
+										},
+									},
 								},
+							},
+							Volumes: []corev1.Volume{
 								{
 									Name: "config",
 									VolumeSource: corev1.VolumeSource{
@@ -311,6 +471,14 @@ func Test_tunnelResource_Deployment(t *testing.T) {
 										},
 									},
 								},
+								{
+									Name: "credentials",
+									VolumeSource: corev1.VolumeSource{
+										Secret: &corev1.SecretVolumeSource{
+											SecretName: "test-tunnel-secret",
+										},
+									},
+								},
 							},
 						},
 					},
@@ -321,9 +489,210 @@ func Test_tunnelResource_Deployment(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			r := NewTunnelResources(tt.fields.Tunnel)
+			tt.want.Spec.Template.Annotations = map[string]string{
+				PodTemplateHashAnnotation: podTemplateHash(tt.want.Spec.Template),
+			}
 			if got := r.Deployment(); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("tunnelResource.Deployment() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func Test_tunnelResource_RemoteConfiguration(t *testing.T) {
+	tests := []struct {
+		name    string
+		tunnel  *cloudflaredv1alpha1.Tunnel
+		want    *cloudflare.TunnelConfiguration
+		wantErr bool
+	}{
+		{
+			name: "default",
+			tunnel: &cloudflaredv1alpha1.Tunnel{
+				Spec: cloudflaredv1alpha1.TunnelSpec{
+					TunnelConfigurationSpec: cloudflaredv1alpha1.TunnelConfigurationSpec{
+						OriginRequest: &cloudflaredv1alpha1.TunnelOriginRequest{
+							ConnectTimeout:       "30s",
+							KeepAliveTimeout:     "1m30s",
+							KeepAliveConnections: 100,
+							NoTLSVerify:          true,
+						},
+					},
+					IngressRules: []cloudflaredv1alpha1.TunnelIngressRule{
+						{
+							Hostname: "foo.example.com",
+							Service:  "http://foo:8000",
+						},
+						{
+							Service: "http_status:404",
+						},
+					},
+				},
+				Status: cloudflaredv1alpha1.TunnelStatus{
+					NetworkRoutes: []string{"10.96.0.0/12"},
+				},
+			},
+			want: &cloudflare.TunnelConfiguration{
+				Ingress: []cloudflare.TunnelIngressRule{
+					{
+						Hostname: "foo.example.com",
+						Service:  "http://foo:8000",
+					},
+					{
+						Service: "http_status:404",
+					},
+				},
+				OriginRequest: &cloudflare.TunnelOriginRequest{
+					ConnectTimeout:       30,
+					KeepAliveTimeout:     90,
+					KeepAliveConnections: 100,
+					NoTLSVerify:          true,
+				},
+				WarpRouting: &cloudflare.TunnelWarpRouting{Enabled: true},
+			},
+		},
+		{
+			name: "invalid duration",
+			tunnel: &cloudflaredv1alpha1.Tunnel{
+				Spec: cloudflaredv1alpha1.TunnelSpec{
+					TunnelConfigurationSpec: cloudflaredv1alpha1.TunnelConfigurationSpec{
+						OriginRequest: &cloudflaredv1alpha1.TunnelOriginRequest{
+							TLSTimeout: "10",
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewTunnelResources(tt.tunnel)
+			got, err := r.RemoteConfiguration()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("tunnelResource.RemoteConfiguration() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tunnelResource.RemoteConfiguration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_tunnelResource_ValidateConfiguration(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    cloudflaredv1alpha1.TunnelSpec
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			spec: cloudflaredv1alpha1.TunnelSpec{
+				TunnelConfigurationSpec: cloudflaredv1alpha1.TunnelConfigurationSpec{
+					OriginRequest: &cloudflaredv1alpha1.TunnelOriginRequest{
+						ConnectTimeout:   "30s",
+						TLSTimeout:       "10s",
+						TCPKeepAlive:     "30s",
+						KeepAliveTimeout: "1m30s",
+					},
+				},
+				IngressRules: []cloudflaredv1alpha1.TunnelIngressRule{
+					{
+						Hostname: "foo.example.com",
+						Path:     "^/api/",
+						Service:  "http://foo:8000",
+					},
+					{
+						Service: "http_status:404",
+					},
+				},
+			},
+		},
+		{
+			name: "partial origin request",
+			spec: cloudflaredv1alpha1.TunnelSpec{
+				TunnelConfigurationSpec: cloudflaredv1alpha1.TunnelConfigurationSpec{
+					OriginRequest: &cloudflaredv1alpha1.TunnelOriginRequest{
+						ConnectTimeout: "30s",
+					},
+				},
+				IngressRules: []cloudflaredv1alpha1.TunnelIngressRule{
+					{
+						Service: "http://foo:8000",
+					},
+				},
+			},
+		},
+		{
+			name: "no rules",
+		},
+		{
+			name: "missing catch-all rule",
+			spec: cloudflaredv1alpha1.TunnelSpec{
+				IngressRules: []cloudflaredv1alpha1.TunnelIngressRule{
+					{
+						Hostname: "foo.example.com",
+						Service:  "http://foo:8000",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid path regex",
+			spec: cloudflaredv1alpha1.TunnelSpec{
+				IngressRules: []cloudflaredv1alpha1.TunnelIngressRule{
+					{
+						Hostname: "foo.example.com",
+						Path:     "^/api/(",
+						Service:  "http://foo:8000",
+					},
+					{
+						Service: "http_status:404",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "service without hostname",
+			spec: cloudflaredv1alpha1.TunnelSpec{
+				IngressRules: []cloudflaredv1alpha1.TunnelIngressRule{
+					{
+						Service: "foo:8000",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "malformed duration",
+			spec: cloudflaredv1alpha1.TunnelSpec{
+				TunnelConfigurationSpec: cloudflaredv1alpha1.TunnelConfigurationSpec{
+					OriginRequest: &cloudflaredv1alpha1.TunnelOriginRequest{
+						ConnectTimeout:   "30",
+						TLSTimeout:       "10s",
+						TCPKeepAlive:     "30s",
+						KeepAliveTimeout: "1m30s",
+					},
+				},
+				IngressRules: []cloudflaredv1alpha1.TunnelIngressRule{
+					{
+						Service: "http://foo:8000",
+					},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewTunnelResources(&cloudflaredv1alpha1.Tunnel{Spec: tt.spec})
+			if err := r.ValidateConfiguration(); (err != nil) != tt.wantErr {
+				t.Errorf("tunnelResource.ValidateConfiguration() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package tunnelroutes
 
 import (
+	"strings"
+
 	"k8s.io/apimachinery/pkg/util/sets"
 
 	cloudflaredv1alpha1 "github.com/prksu/cloudflared-controller/api/v1alpha1"
@@ -43,3 +45,20 @@ func FromTunnelStatus(status cloudflaredv1alpha1.TunnelStatus) []string {
 func Difference(r1, r2 []string) []string {
 	return sets.NewString(r1...).Difference(sets.NewString(r2...)).List()
 }
+
+// ZoneOf returns the zone to which the hostname belongs by the longest suffix match,
+// or empty string when the hostname does not belong to any of the zones.
+func ZoneOf(hostname string, zones []string) string {
+	var result string
+	for _, zone := range zones {
+		if hostname != zone && !strings.HasSuffix(hostname, "."+zone) {
+			continue
+		}
+
+		if len(zone) > len(result) {
+			result = zone
+		}
+	}
+
+	return result
+}
@@ -124,6 +124,14 @@ func TestDifference(t *testing.T) {
 			},
 			want: []string{"bar.example.com"},
 		},
+		{
+			name: "routes no longer desired",
+			args: args{
+				r1: []string{"foo.example.com", "bar.example.com"},
+				r2: []string{"bar.example.com", "baz.example.com"},
+			},
+			want: []string{"foo.example.com"},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -133,3 +141,63 @@ func TestDifference(t *testing.T) {
 		})
 	}
 }
+
+func TestZoneOf(t *testing.T) {
+	type args struct {
+		hostname string
+		zones    []string
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			name: "subdomain",
+			args: args{
+				hostname: "foo.example.com",
+				zones:    []string{"example.org", "example.com"},
+			},
+			want: "example.com",
+		},
+		{
+			name: "root domain",
+			args: args{
+				hostname: "example.com",
+				zones:    []string{"example.com"},
+			},
+			want: "example.com",
+		},
+		{
+			name: "longest suffix",
+			args: args{
+				hostname: "foo.dev.example.com",
+				zones:    []string{"example.com", "dev.example.com"},
+			},
+			want: "dev.example.com",
+		},
+		{
+			name: "wildcard",
+			args: args{
+				hostname: "*.example.com",
+				zones:    []string{"example.com"},
+			},
+			want: "example.com",
+		},
+		{
+			name: "suffix without dot boundary",
+			args: args{
+				hostname: "fooexample.com",
+				zones:    []string{"example.com"},
+			},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ZoneOf(tt.args.hostname, tt.args.zones); got != tt.want {
+				t.Errorf("ZoneOf() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
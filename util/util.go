@@ -18,7 +18,14 @@ package util
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
 	"strconv"
 
 	corev1 "k8s.io/api/core/v1"
@@ -31,6 +38,12 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	cloudflaredv1alpha1 "github.com/prksu/cloudflared-controller/api/v1alpha1"
+	"github.com/prksu/cloudflared-controller/cloudflare"
+)
+
+var (
+	ErrMissingAccountID = errors.New("missing cloudflare account id, either originCert or accountID is required")
+	ErrMissingZone      = errors.New("missing cloudflare zone, either originCert, zone or zoneID is required")
 )
 
 func FormatIngressServiceBackend(svc *networkingv1.IngressServiceBackend) string {
@@ -86,3 +99,135 @@ func TunnelConfigurationFromIngress(ctx context.Context, crclient client.Client,
 
 	return tc, nil
 }
+
+// getAPIToken returns the API token from the Secret key reference in the given namespace, along with
+// the resourceVersion of the Secret. The Secret could only be in the same namespace of the referrer, so
+// one namespace cannot use the token of another. CF_API_TOKEN env var is used when ref is nil.
+func getAPIToken(ctx context.Context, crclient client.Client, namespace string, ref *corev1.SecretKeySelector) (string, string, error) {
+	if ref == nil {
+		return os.Getenv(cloudflare.APITokenEnv), "", nil
+	}
+
+	secret := &corev1.Secret{}
+	if err := crclient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: ref.Name}, secret); err != nil {
+		return "", "", err
+	}
+
+	token, ok := secret.Data[ref.Key]
+	if !ok {
+		return "", "", fmt.Errorf("secret %s/%s does not have key %q", namespace, ref.Name, ref.Key)
+	}
+
+	return string(token), secret.ResourceVersion, nil
+}
+
+// cloudflareCredentials are the credentials referenced by a TunnelConfigurationSpec.
+type cloudflareCredentials struct {
+	apiToken   string
+	originCert []byte
+	// version changes whenever any of the referenced Secrets changes.
+	version string
+}
+
+func getCloudflareCredentials(ctx context.Context, crclient client.Client, namespace string, spec cloudflaredv1alpha1.TunnelConfigurationSpec) (*cloudflareCredentials, error) {
+	apiToken, apiTokenVersion, err := getAPIToken(ctx, crclient, namespace, spec.APITokenSecretRef)
+	if err != nil {
+		return nil, err
+	}
+
+	creds := &cloudflareCredentials{apiToken: apiToken}
+	var originCertVersion string
+	if spec.OriginCert != nil {
+		ocsecret, err := GetOriginCertSecret(ctx, crclient, namespace, spec.OriginCert)
+		if err != nil {
+			return nil, err
+		}
+
+		creds.originCert = ocsecret.Data["cert.pem"]
+		originCertVersion = ocsecret.ResourceVersion
+	}
+
+	creds.version = apiTokenVersion + "/" + originCertVersion
+	return creds, nil
+}
+
+// newCloudflareClient creates cloudflare client from the credentials of the TunnelConfigurationSpec.
+func newCloudflareClient(ctx context.Context, creds *cloudflareCredentials, spec cloudflaredv1alpha1.TunnelConfigurationSpec, extraOpts ...cloudflare.ClientOption) (cloudflare.Client, error) {
+	opts := []cloudflare.ClientOption{
+		cloudflare.WithAPIToken(creds.apiToken),
+	}
+
+	if spec.OriginCert != nil {
+		opts = append(opts, cloudflare.WithOriginCert(creds.originCert))
+	}
+
+	if spec.AccountID != "" {
+		opts = append(opts, cloudflare.WithAccountID(spec.AccountID))
+	}
+
+	if spec.ZoneID != "" {
+		opts = append(opts, cloudflare.WithZoneID(spec.ZoneID))
+	}
+
+	opts = append(opts, cloudflare.WithRetryPolicy(cloudflare.DefaultRetryPolicy))
+	opts = append(opts, extraOpts...)
+
+	cfclient, err := cloudflare.NewClient(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfclient.AccountID() == "" {
+		return nil, ErrMissingAccountID
+	}
+
+	if spec.ZoneID == "" && spec.Zone != "" {
+		zone, err := cfclient.Zones().GetByName(ctx, spec.Zone)
+		if err != nil {
+			return nil, err
+		}
+
+		return cloudflare.NewClient(append(opts, cloudflare.WithZoneID(zone.ID))...)
+	}
+
+	if cfclient.ZoneID() == "" {
+		return nil, ErrMissingZone
+	}
+
+	return cfclient, nil
+}
+
+// NewCloudflareTransport creates the transport of cloudflare clients going through the proxy and
+// trusting the CA bundle file along with the system CAs. The proxy of the environment variables
+// (HTTPS_PROXY and NO_PROXY) is used when proxyURL is empty.
+func NewCloudflareTransport(proxyURL, caFile string) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxyURL != "" {
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			return nil, err
+		}
+
+		transport.Proxy = http.ProxyURL(u)
+	}
+
+	if caFile != "" {
+		caBundle, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(caBundle) {
+			return nil, fmt.Errorf("no certificate found in %s", caFile)
+		}
+
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	return transport, nil
+}
@@ -18,6 +18,9 @@ package util
 
 import (
 	"context"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -32,6 +35,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	cloudflaredv1alpha1 "github.com/prksu/cloudflared-controller/api/v1alpha1"
+	"github.com/prksu/cloudflared-controller/cloudflare"
 )
 
 func TestGetOriginCertSecret(t *testing.T) {
@@ -270,3 +274,57 @@ func TestTunnelConfigurationFromIngress(t *testing.T) {
 		})
 	}
 }
+
+func TestNewCloudflareTransport(t *testing.T) {
+	dir := t.TempDir()
+	emptyCAFile := filepath.Join(dir, "empty.pem")
+	if err := ioutil.WriteFile(emptyCAFile, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		proxyURL  string
+		caFile    string
+		wantProxy string
+		wantErr   bool
+	}{
+		{
+			name: "default",
+		},
+		{
+			name:      "proxy",
+			proxyURL:  "http://proxy.example.com:3128",
+			wantProxy: "http://proxy.example.com:3128",
+		},
+		{
+			name:    "missing ca file",
+			caFile:  filepath.Join(dir, "missing.pem"),
+			wantErr: true,
+		},
+		{
+			name:    "ca file without certificate",
+			caFile:  emptyCAFile,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewCloudflareTransport(tt.proxyURL, tt.caFile)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewCloudflareTransport() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if err != nil || tt.wantProxy == "" {
+				return
+			}
+
+			req, _ := http.NewRequest(http.MethodGet, cloudflare.DefaultBaseURL, nil)
+			proxy, err := got.Proxy(req)
+			if err != nil || proxy.String() != tt.wantProxy {
+				t.Errorf("NewCloudflareTransport() proxy = %v, want %v", proxy, tt.wantProxy)
+			}
+		})
+	}
+}